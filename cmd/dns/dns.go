@@ -23,8 +23,12 @@ package dns
 
 import (
 	"os"
+	"os/signal"
 
+	"github.com/soner3/net-scan/cmd/history"
+	"github.com/soner3/net-scan/dns"
 	"github.com/soner3/net-scan/dns/action"
+	"github.com/soner3/net-scan/util"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -47,10 +51,39 @@ The results are printed in a structured format per host. Use the --file flag to
 Example:
   net-scan dns --file hosts.txt
 
-Each line in the input file should contain a single hostname.`,
+Each line in the input file should contain a single hostname, address, CIDR block or range.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		filename := viper.GetString("file")
-		return action.DnsAction(os.Stdout, filename)
+		progress, err := util.NewProgress(os.Stderr, viper.GetString("progress"), viper.GetDuration("progress-interval"))
+		if err != nil {
+			return err
+		}
+		limiter, err := util.NewLimiter(viper.GetFloat64("max-rate"), viper.GetDuration("host-delay"), viper.GetDuration("jitter"))
+		if err != nil {
+			return err
+		}
+		cfg := &action.Config{
+			Filename: viper.GetString("file"),
+			Output:   viper.GetString("output"),
+			Progress: progress,
+			Limiter:  limiter,
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		rec, err := history.NewRecorder("dns")
+		if err != nil {
+			return err
+		}
+		cfg.OnResult = func(res *dns.DnsResult) {
+			rec.Add(res)
+		}
+		if err := action.DnsAction(ctx, os.Stdout, cfg); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			rec.Interrupt()
+		}
+		return history.Save(rec)
 	},
 }
 
@@ -0,0 +1,59 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package history
+
+import (
+	"os"
+
+	"github.com/soner3/net-scan/history/action"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// deleteCmd represents the delete command
+var deleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Deletes runs using args: <id1> <id2> ... <idN>",
+	Long: `Deletes saved runs by their IDs or unambiguous prefixes of them.
+--older-than deletes all runs which started longer ago.
+
+Example usage:
+  net-scan history delete 20250101-120000.123456
+  net-scan history delete --older-than 720h`,
+	Aliases:      []string{"d"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := Store()
+		if err != nil {
+			return err
+		}
+		return action.DeleteAction(os.Stdout, store, args, viper.GetDuration("history.older-than"))
+	},
+}
+
+func init() {
+	deleteCmd.Flags().Duration("older-than", 0, "Delete all runs which started longer ago")
+
+	viper.BindPFlag("history.older-than", deleteCmd.Flags().Lookup("older-than"))
+
+	HistoryCmd.AddCommand(deleteCmd)
+}
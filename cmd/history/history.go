@@ -0,0 +1,116 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/soner3/net-scan/history"
+	"github.com/soner3/net-scan/host"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// historyCmd represents the history command
+var HistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Look back at saved runs of scan, ping, dns and http",
+	Long: `Manage the runs saved with the global --save-history flag.
+
+Every saved run holds the command, its arguments and configuration, the
+entries of the host file, the start and end time and the full results of all
+hosts. Runs are stored as JSON files in --history-dir
+(default: $HOME/.net-scan/history) and are named by their start time, any
+unambiguous prefix of the ID selects a run.
+
+Subcommands:
+  - list:    Show the saved runs
+  - show:    Show a run with its configuration and results
+  - delete:  Remove runs
+
+Example usage:
+  net-scan scan -p 22,80 --save-history
+  net-scan history list --command scan --host 10.0.0.1
+  net-scan history show 20250101-1200
+  net-scan history delete --older-than 720h`,
+}
+
+func init() {
+	HistoryCmd.SetErrPrefix("History Error:\n\t")
+}
+
+// Store returns the store of the history directory
+func Store() (*history.Store, error) {
+	dir := viper.GetString("history-dir")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(home, ".net-scan", "history")
+	}
+	return history.NewStore(dir), nil
+}
+
+// NewRecorder starts recording a run of the command if --save-history is
+// set and returns nil otherwise. It fails if the host file can't be read.
+func NewRecorder(command string) (*history.Recorder, error) {
+	if !viper.GetBool("save-history") {
+		return nil, nil
+	}
+
+	// The global settings and the ones of the command
+	config := map[string]any{}
+	for key, value := range viper.AllSettings() {
+		if _, ok := value.(map[string]any); !ok {
+			config[key] = value
+		}
+	}
+	for key, value := range viper.GetStringMap(command) {
+		config[command+"."+key] = value
+	}
+
+	hl := host.NewHostList()
+	if err := hl.Load(viper.GetString("file")); err != nil {
+		return nil, err
+	}
+	return history.NewRecorder(command, os.Args[1:], config, hl.Hosts), nil
+}
+
+// Save the recorded run, a nil recorder saves nothing
+func Save(rec *history.Recorder) error {
+	if rec == nil {
+		return nil
+	}
+	store, err := Store()
+	if err != nil {
+		return err
+	}
+	id, err := rec.Save(store)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "Saved run:", id)
+	return nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package history
+
+import (
+	"os"
+
+	"github.com/soner3/net-scan/history/action"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Displays the saved runs",
+	Long: `Displays the saved runs, oldest first, with their ID, command, start
+time, duration, number of results and the entries of the host file.
+
+--command only lists runs of a command, --host only runs which probed a
+host or had it in the host file.
+
+Example usage:
+  net-scan history list
+  net-scan history list --command scan --host 10.0.0.1 -o json`,
+	Aliases:      []string{"l"},
+	SilenceUsage: true,
+	Args:         cobra.MaximumNArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := Store()
+		if err != nil {
+			return err
+		}
+		return action.ListAction(os.Stdout, store, viper.GetString("history.command"), viper.GetString("history.host"), viper.GetString("output"))
+	},
+}
+
+func init() {
+	listCmd.Flags().String("command", "", "Only list runs of the command (scan, ping, dns, http)")
+	listCmd.Flags().String("host", "", "Only list runs of the host")
+
+	viper.BindPFlag("history.command", listCmd.Flags().Lookup("command"))
+	viper.BindPFlag("history.host", listCmd.Flags().Lookup("host"))
+
+	HistoryCmd.AddCommand(listCmd)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package history
+
+import (
+	"os"
+
+	"github.com/soner3/net-scan/history/action"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// showCmd represents the show command
+var showCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Displays a saved run with its configuration and results",
+	Long: `Displays a saved run with its arguments, configuration, the entries of
+the host file and the results of all hosts. Any unambiguous prefix of the ID
+selects the run.
+
+Example usage:
+  net-scan history show 20250101-120000.123456
+  net-scan history show 20250101-1200 -o json`,
+	Aliases:      []string{"s"},
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := Store()
+		if err != nil {
+			return err
+		}
+		return action.ShowAction(os.Stdout, store, args[0], viper.GetString("output"))
+	},
+}
+
+func init() {
+	HistoryCmd.AddCommand(showCmd)
+}
@@ -34,6 +34,12 @@ var HostCmd = &cobra.Command{
 This command allows you to add, delete, or list commonly used targets
 so you don't have to retype IPs or domains for every scan.
 
+Besides hostnames and addresses, a host entry may describe many targets at once.
+They are expanded when a command runs (at most 65536 hosts per entry):
+  - CIDR blocks:      10.0.0.0/24, 2001:db8::/120
+  - Octet ranges:     192.168.1.10-50
+  - Octet wildcards:  10.0.*.1
+
 Subcommands:
   - list:    Show all saved hosts
   - add:     Add a new host to the list
@@ -41,6 +47,7 @@ Subcommands:
 
 Example usage:
   net-scan host add myserver 192.168.1.10
+  net-scan host add 10.0.0.0/24 192.168.1.10-50
   net-scan host list
   net-scan host delete myserver`,
 }
@@ -25,7 +25,10 @@ import (
 	"os"
 	"time"
 
+	"github.com/soner3/net-scan/cmd/history"
+	"github.com/soner3/net-scan/http"
 	"github.com/soner3/net-scan/http/action"
+	"github.com/soner3/net-scan/util"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -37,10 +40,14 @@ var HttpCmd = &cobra.Command{
 	Long: `The http command sends periodic HTTP requests to hosts defined in a file.
 
 You can configure the frequency and timeout of the requests. Optionally, you can
-enable HTTPS with the --secure flag.
+enable HTTPS with the --secure flag. --source-ip, --iface (Linux only) and
+--source-port bind the connections to a local address, interface and port.
+--proxy connects through a SOCKS5 or HTTP CONNECT proxy.
 
 Example:
   net-scan http --call-frequency 1s --timeout 5s --secure
+  net-scan http --iface eth1
+  net-scan http --proxy http://proxy.internal:3128
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg := &action.Config{
@@ -48,8 +55,39 @@ Example:
 			CallFrequency: viper.GetDuration("http.call-frequency"),
 			Timeout:       viper.GetDuration("http.timeout"),
 			Secure:        viper.GetBool("http.secure"),
+			Output:        viper.GetString("output"),
 		}
-		return action.HttpAction(os.Stdout, cfg)
+		progress, err := util.NewProgress(os.Stderr, viper.GetString("progress"), viper.GetDuration("progress-interval"))
+		if err != nil {
+			return err
+		}
+		cfg.Progress = progress
+		limiter, err := util.NewLimiter(viper.GetFloat64("max-rate"), viper.GetDuration("host-delay"), viper.GetDuration("jitter"))
+		if err != nil {
+			return err
+		}
+		cfg.Limiter = limiter
+		source, err := util.NewSource(viper.GetString("http.source-ip"), viper.GetString("http.iface"), viper.GetInt("http.source-port"))
+		if err != nil {
+			return err
+		}
+		cfg.Source = source
+		proxy, err := util.NewProxy(viper.GetString("http.proxy"))
+		if err != nil {
+			return err
+		}
+		cfg.Proxy = proxy
+		rec, err := history.NewRecorder("http")
+		if err != nil {
+			return err
+		}
+		cfg.OnResult = func(res *http.Result) {
+			rec.Add(res)
+		}
+		if err := action.HttpAction(cmd.Context(), os.Stdout, cfg); err != nil {
+			return err
+		}
+		return history.Save(rec)
 	},
 }
 
@@ -60,7 +98,16 @@ func init() {
 	HttpCmd.Flags().DurationP("timeout", "t", 5*time.Second, "Request timeout duration")
 	HttpCmd.Flags().BoolP("secure", "s", true, "Use HTTPS instead of HTTP")
 
+	HttpCmd.Flags().String("proxy", "", "Connect through a proxy (socks5://host:port or http://host:port)")
+	HttpCmd.Flags().String("source-ip", "", "Local address to connect from")
+	HttpCmd.Flags().StringP("iface", "I", "", "Network interface to connect from (Linux only)")
+	HttpCmd.Flags().Int("source-port", 0, "Local port to connect from")
+
 	viper.BindPFlag("http.call-frequency", HttpCmd.Flags().Lookup("call-frequency"))
 	viper.BindPFlag("http.timeout", HttpCmd.Flags().Lookup("timeout"))
 	viper.BindPFlag("http.secure", HttpCmd.Flags().Lookup("secure"))
+	viper.BindPFlag("http.proxy", HttpCmd.Flags().Lookup("proxy"))
+	viper.BindPFlag("http.source-ip", HttpCmd.Flags().Lookup("source-ip"))
+	viper.BindPFlag("http.iface", HttpCmd.Flags().Lookup("iface"))
+	viper.BindPFlag("http.source-port", HttpCmd.Flags().Lookup("source-port"))
 }
@@ -25,7 +25,10 @@ import (
 	"os"
 	"time"
 
+	"github.com/soner3/net-scan/cmd/history"
+	"github.com/soner3/net-scan/ping"
 	"github.com/soner3/net-scan/ping/action"
+	"github.com/soner3/net-scan/util"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -71,8 +74,29 @@ Examples:
 			Iface:      viper.GetString("ping.iface"),
 			Tclass:     viper.GetInt("ping.tclass"),
 			Priveleged: viper.GetBool("ping.privileged"),
+			Output:     viper.GetString("output"),
 		}
-		return action.PingAction(os.Stdout, cfg)
+		progress, err := util.NewProgress(os.Stderr, viper.GetString("progress"), viper.GetDuration("progress-interval"))
+		if err != nil {
+			return err
+		}
+		cfg.Progress = progress
+		limiter, err := util.NewLimiter(viper.GetFloat64("max-rate"), viper.GetDuration("host-delay"), viper.GetDuration("jitter"))
+		if err != nil {
+			return err
+		}
+		cfg.Limiter = limiter
+		rec, err := history.NewRecorder("ping")
+		if err != nil {
+			return err
+		}
+		cfg.OnResult = func(res *ping.Result) {
+			rec.Add(res)
+		}
+		if err := action.PingAction(cmd.Context(), os.Stdout, cfg); err != nil {
+			return err
+		}
+		return history.Save(rec)
 	},
 }
 
@@ -24,12 +24,16 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/soner3/net-scan/cmd/dns"
+	"github.com/soner3/net-scan/cmd/history"
 	"github.com/soner3/net-scan/cmd/host"
 	"github.com/soner3/net-scan/cmd/http"
 	"github.com/soner3/net-scan/cmd/ping"
 	"github.com/soner3/net-scan/cmd/scan"
+	"github.com/soner3/net-scan/cmd/verify"
+	"github.com/soner3/net-scan/util"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -48,13 +52,15 @@ It supports:
   - Ping checks
   - Banner grabbing
   - HTTP availability checks
+  - A history of saved runs
+  - Verifying expected port states against a policy
 
 Example usage:
 
   net-scan scan --ports 22,80,443 --file hosts.txt
 
 Ideal for sysadmins, DevOps engineers, and security enthusiasts who need fast and flexible network scans.`,
-	Version: "0.1",
+	Version: util.VERSION,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -72,6 +78,23 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.net-scan.yaml)")
 	rootCmd.PersistentFlags().StringP("file", "f", "net-scan.hosts", "Name of file to save and load hosts")
 	viper.BindPFlag("file", rootCmd.PersistentFlags().Lookup("file"))
+	rootCmd.PersistentFlags().StringP("output", "o", util.OUTPUT_TEXT, "Output format (text, json, jsonl, csv, tsv; nmap-xml for scan; junit for scan, ping, http and verify; sarif for scan and verify)")
+	viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output"))
+	rootCmd.PersistentFlags().Float64("max-rate", 0, "Maximum probes per second across all hosts (0 = unlimited)")
+	viper.BindPFlag("max-rate", rootCmd.PersistentFlags().Lookup("max-rate"))
+	rootCmd.PersistentFlags().Duration("host-delay", 0, "Minimum delay between two probes to the same host")
+	viper.BindPFlag("host-delay", rootCmd.PersistentFlags().Lookup("host-delay"))
+	rootCmd.PersistentFlags().Duration("jitter", 0, "Random extra delay of up to this duration before every probe")
+	viper.BindPFlag("jitter", rootCmd.PersistentFlags().Lookup("jitter"))
+	rootCmd.PersistentFlags().String("progress", util.PROGRESS_AUTO, "Progress on stderr (auto, bar, json, none); auto shows a status line if stderr is a terminal")
+	viper.BindPFlag("progress", rootCmd.PersistentFlags().Lookup("progress"))
+	rootCmd.PersistentFlags().Duration("progress-interval", 10*time.Second, "Interval of the JSON status lines of --progress json")
+	viper.BindPFlag("progress-interval", rootCmd.PersistentFlags().Lookup("progress-interval"))
+
+	rootCmd.PersistentFlags().Bool("save-history", false, "Save the run with its results to the history (see net-scan history)")
+	viper.BindPFlag("save-history", rootCmd.PersistentFlags().Lookup("save-history"))
+	rootCmd.PersistentFlags().String("history-dir", "", "Directory of the saved runs (default is $HOME/.net-scan/history)")
+	viper.BindPFlag("history-dir", rootCmd.PersistentFlags().Lookup("history-dir"))
 
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	versionTemplate := `{{printf "%s: %s - version %s\n" .Name .Short .Version}}`
@@ -81,6 +104,8 @@ func init() {
 	rootCmd.AddCommand(ping.PingCmd)
 	rootCmd.AddCommand(http.HttpCmd)
 	rootCmd.AddCommand(dns.DnsCmd)
+	rootCmd.AddCommand(history.HistoryCmd)
+	rootCmd.AddCommand(verify.VerifyCmd)
 }
 
 // initConfig reads in config file and ENV variables if set.
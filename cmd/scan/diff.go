@@ -0,0 +1,62 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package scan
+
+import (
+	"os"
+
+	"github.com/soner3/net-scan/cmd/history"
+	"github.com/soner3/net-scan/scan/action"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <old-run> <new-run>",
+	Short: "Reports the changes between two saved scan runs",
+	Long: `Compares two scan runs saved with --save-history and reports hosts which
+appeared or vanished, ports which were opened or closed and changed banners
+and services of open ports. Any unambiguous prefix of a run ID selects the run
+(see net-scan history list --command scan).
+
+The command exits with a non-zero code if there are changes, so it can gate CI
+pipelines and cron jobs.
+
+Example usage:
+  net-scan scan diff 20250101-020000 20250102-020000
+  net-scan scan diff 20250101 20250102 -o json`,
+	Aliases:      []string{"d"},
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := history.Store()
+		if err != nil {
+			return err
+		}
+		return action.DiffAction(os.Stdout, store, args[0], args[1], viper.GetString("output"))
+	},
+}
+
+func init() {
+	ScanCmd.AddCommand(diffCmd)
+}
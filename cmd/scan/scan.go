@@ -22,10 +22,20 @@ THE SOFTWARE.
 package scan
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/soner3/net-scan/cmd/history"
+	hist "github.com/soner3/net-scan/history"
+	"github.com/soner3/net-scan/scan"
 	"github.com/soner3/net-scan/scan/action"
+	"github.com/soner3/net-scan/scan/service"
+	"github.com/soner3/net-scan/util"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -36,42 +46,272 @@ var ScanCmd = &cobra.Command{
 	Short: "Scan ports on hosts defined in a host file",
 	Long: `The scan command connects to specified ports on target hosts
 using a selected network protocol (e.g., tcp, udp, etc.). 
-You can define specific ports or port ranges and apply filters to show only open, closed, or timeout states.
+You can define specific ports or port ranges and apply filters to show only ports in a given state.
+
+Port expressions are comma separated lists of:
+  80            a single port
+  80-90         a range
+  -1024         a range from port 1
+  60000-        a range up to port 65535
+  ssh, https    a well known service name
+  !135-139      an exclusion
+A "T:" or "U:" prefix selects TCP or UDP for the following ports, e.g.
+T:22,80,U:53,161 probes 53 and 161 over UDP during a TCP scan.
+
+--top-ports N adds the N most common ports and --preset the ports of a preset
+(db, web, windows) of the network protocol. Exclusions of --ports apply to them
+as well, e.g. --top-ports 100 -p !23. The port table knows the top ` +
+		strconv.Itoa(scan.KnownPorts(scan.PROTO_TCP)) + ` TCP and
+` + strconv.Itoa(scan.KnownPorts(scan.PROTO_UDP)) + ` UDP ports; larger values of --top-ports are rejected.
+
+--all-addresses scans every IPv4 and IPv6 address of a host (only the ones of
+the address family of tcp4/tcp6/udp4/udp6) and reports the ports per address.
+
+Before the ports of a host are scanned, a discovery checks whether the host is
+up: ICMP echo requests, TCP connections to a few common ports (an accepted and
+a refused connection both count, with --syn SYNs are sent instead) and ARP on
+local IPv4 segments. Hosts without any answer are reported as down and skipped.
+The discovery is on by default, earlier versions scanned every host right away:
+--skip-discovery keeps that behavior and sends nothing but the probes.
+Unprivileged ICMP needs net.ipv4.ping_group_range on Linux, --privileged uses
+raw sockets instead.
+
+--source-ip, --iface and --source-port bind every probe to a local address,
+interface (Linux only) and port, e.g. to test firewall rules from a specific
+interface of a multi-homed host. A fixed source port is shared by all probes;
+discovery and service detection connect from ports chosen by the system, they
+would collide with the probes of the same ports.
+
+--syn sends SYN packets on a raw socket instead of connecting (half-open scan),
+so the targets' applications never see a connection. A SYN/ACK marks a port
+open and is answered with a RST, a RST marks it closed and silence filtered. It
+needs root or CAP_NET_RAW (like ping --privileged), Linux and IPv4 (tcp, tcp4).
+
+--proxy connects to the ports through a SOCKS5 (socks5://[user:pass@]host:port)
+or HTTP CONNECT (http://[user:pass@]host:port) proxy, which also resolves the
+host names. The answers of the proxy become port states: a refused connection
+is closed, an unreachable host or network is unreachable and a timeout is
+filtered. Only tcp ports and the tcp discovery go through the proxy.
+
+--compare-last compares the results with the last scan run saved with
+--save-history and reports the changes on stderr: hosts which appeared or
+vanished, opened and closed ports and changed banners and services. The command
+exits with a non-zero code if there are changes. An interrupted scan is neither
+saved nor compared. "scan diff" compares two saved runs.
+
+--retries repeats probes which got no answer. --adaptive-timeout measures the
+round trip times of every host and derives its timeout like TCP does (at least
+100ms, at most --timeout). The estimates are part of the result.
+
+Port states:
+  open         the connection was accepted
+  closed       the connection was refused
+  filtered     no response before the timeout (alias: timeout)
+  unreachable  the host or network is unreachable
+  error        a local error prevented the probe (e.g. too many open files)
+  open|filtered  UDP only: no reply, the port is either open or filtered
+
+UDP scans send a protocol specific probe (DNS for 53, NTP for 123, SNMP for 161
+and an empty datagram otherwise). A reply marks the port open and an ICMP port
+unreachable marks it closed.
+
+Service detection sends the probes of an embedded database and matches the
+answers against regular expressions. Use --service-db to add probes and matches
+for internal services, the file uses the same format as the embedded database:
+
+  Probe NULL q||
+  match myapp m|^MYAPP ([\d.]+)| p/My App/ v/$1/
 
 Supported network protocols include:
   tcp, tcp4, tcp6, udp, udp4, udp6, ip, ip4, ip6, unix, unixgram, unixpacket.
 
 Examples:
   net-scan scan -p 22,80,443
-  net-scan scan -r 20-100 -t 2s
+  net-scan scan -p ssh,https,8000-8100 -t 2s
+  net-scan scan -p -1024,!135-139
+  net-scan scan -p T:22,80,U:53,123
+  net-scan scan --top-ports 100
+  net-scan scan --preset web,db -p 9000-9010
   net-scan scan -p 53,123 -n udp -s open
+  net-scan scan --top-ports 100 --discovery tcp --discovery-ports 22,443
+  net-scan scan -p 22,80 --skip-discovery
+  net-scan scan -p 22,443 -I eth1 --source-port 53
+  net-scan scan -p 22,3306 --proxy socks5://bastion:1080
+  sudo net-scan scan --top-ports 100 --syn
+  net-scan scan -r 1-65535 -c 1000 --host-concurrency 200
+  net-scan scan --top-ports 100 --adaptive-timeout --retries 2
+  net-scan scan -p 21,22,25,80 --banner -s open
+  net-scan scan -p 22,80,6379 -V --service-db internal.db
+  net-scan scan -p 22,80,443 -V -o nmap-xml > scan.xml
+  net-scan scan --top-ports 100 -V -o sarif > scan.sarif
   net-scan scan --config .net-scan.yaml
+  net-scan scan --top-ports 100 --save-history --compare-last
+  net-scan scan diff 20250101 20250102
 `,
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		filename := viper.GetString("file")
-		ports := viper.GetIntSlice("scan.ports")
+		// Config files may hold the ports as a list
+		ports := strings.Join(viper.GetStringSlice("scan.ports"), ",")
 		portRange := viper.GetString("scan.port-range")
-		network := viper.GetString("scan.network")
-		timeout := viper.GetDuration("scan.timeout")
 		filter := viper.GetString("scan.filter-state")
+		opts := &scan.Options{
+			Network:         viper.GetString("scan.network"),
+			Timeout:         viper.GetDuration("scan.timeout"),
+			Concurrency:     viper.GetInt("scan.concurrency"),
+			HostConcurrency: viper.GetInt("scan.host-concurrency"),
+			Banner:          viper.GetBool("scan.banner"),
+			AllAddresses:    viper.GetBool("scan.all-addresses"),
+			Syn:             viper.GetBool("scan.syn"),
+			Retries:         viper.GetInt("scan.retries"),
+			Adaptive:        viper.GetBool("scan.adaptive-timeout"),
+		}
+
+		if !viper.GetBool("scan.skip-discovery") {
+			opts.Discovery = &scan.Discovery{
+				Methods:    viper.GetStringSlice("scan.discovery"),
+				Ports:      viper.GetIntSlice("scan.discovery-ports"),
+				Timeout:    viper.GetDuration("scan.discovery-timeout"),
+				Privileged: viper.GetBool("scan.privileged"),
+			}
+			if opts.Discovery.Timeout == 0 {
+				opts.Discovery.Timeout = opts.Timeout
+			}
+		}
+
+		if viper.GetBool("scan.service-detect") {
+			db, err := service.Load(viper.GetString("scan.service-db"))
+			if err != nil {
+				return err
+			}
+			opts.Services = db
+		}
+
+		cfg := action.NewConfig(filename, ports, portRange, filter, viper.GetString("output"), opts)
+		cfg.TopPorts = viper.GetInt("scan.top-ports")
+		cfg.Presets = viper.GetStringSlice("scan.preset")
+		progress, err := util.NewProgress(os.Stderr, viper.GetString("progress"), viper.GetDuration("progress-interval"))
+		if err != nil {
+			return err
+		}
+		cfg.Progress = progress
+		limiter, err := util.NewLimiter(viper.GetFloat64("max-rate"), viper.GetDuration("host-delay"), viper.GetDuration("jitter"))
+		if err != nil {
+			return err
+		}
+		opts.Limiter = limiter
+		source, err := util.NewSource(viper.GetString("scan.source-ip"), viper.GetString("scan.iface"), viper.GetInt("scan.source-port"))
+		if err != nil {
+			return err
+		}
+		opts.Source = source
+		proxy, err := util.NewProxy(viper.GetString("scan.proxy"))
+		if err != nil {
+			return err
+		}
+		opts.Proxy = proxy
+
+		// The last run is looked up before this one is saved
+		var last *hist.Run
+		if viper.GetBool("scan.compare-last") {
+			store, err := history.Store()
+			if err != nil {
+				return err
+			}
+			if last, err = store.Last("scan"); err != nil && !errors.Is(err, hist.ErrNotFound) {
+				return err
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		rec, err := history.NewRecorder("scan")
+		if err != nil {
+			return err
+		}
+		results := []scan.ScanResult{}
+		cfg.OnResult = func(res *scan.ScanResult) {
+			rec.Add(res)
+			results = append(results, *res)
+		}
+		// An interrupted run is incomplete, it is neither saved nor compared
+		if err := action.ScanAction(ctx, os.Stdout, cfg); err != nil {
+			return err
+		}
+		if err := history.Save(rec); err != nil {
+			return err
+		}
 
-		return action.ScanAction(os.Stdout, action.NewConfig(filename, ports, portRange, network, timeout, filter))
+		if !viper.GetBool("scan.compare-last") {
+			return nil
+		}
+		if last == nil {
+			fmt.Fprintln(os.Stderr, "No saved scan run to compare with")
+			return nil
+		}
+		lastResults, err := action.RunResults(last)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Changes since run %s:\n", last.ID)
+		return action.WriteDiff(os.Stderr, lastResults, results, util.OUTPUT_TEXT)
 	},
 }
 
 func init() {
 	ScanCmd.SetErrPrefix("Scan Error:\n\t")
 
-	ScanCmd.Flags().IntSliceP("ports", "p", []int{}, "Ports to scan on the target hosts (e.g., 22,80,443)")
-	ScanCmd.Flags().StringP("port-range", "r", "", "Port range to scan on the target hosts (e.g., 20-100)")
+	ScanCmd.Flags().StringP("ports", "p", "", "Port expression to scan on the target hosts (e.g., 22,80-90,https,T:80,U:53,!81)")
+	ScanCmd.Flags().StringP("port-range", "r", "", "Port range to scan on the target hosts (e.g., 20-100), same syntax as --ports")
+	ScanCmd.Flags().Int("top-ports", 0, fmt.Sprintf("Scan the N most common ports of the network protocol (max %d tcp, %d udp)", scan.KnownPorts(scan.PROTO_TCP), scan.KnownPorts(scan.PROTO_UDP)))
+	ScanCmd.Flags().StringSlice("preset", []string{}, fmt.Sprintf("Scan the ports of presets (%s)", strings.Join(scan.Presets(), ", ")))
 	ScanCmd.Flags().StringP("network", "n", "tcp", "Network protocol to use (tcp, udp, tcp4, tcp6, udp4, udp6, ip, ip4, ip6, unix, unixgram, unixpacket)")
 	ScanCmd.Flags().DurationP("timeout", "t", time.Millisecond*1000, "Timeout per port")
-	ScanCmd.Flags().StringP("filter-state", "s", "", "Filter scanned results by port state (open, closed, timeout)")
+	ScanCmd.Flags().Bool("skip-discovery", false, "Scan every host without checking first whether it is up")
+	ScanCmd.Flags().StringSlice("discovery", scan.DiscoveryMethods(), "Host discovery methods (icmp, tcp, arp)")
+	ScanCmd.Flags().IntSlice("discovery-ports", scan.DISCOVERY_PORTS, "Ports the tcp discovery connects to")
+	ScanCmd.Flags().Duration("discovery-timeout", 0, "Timeout of the discovery probes (default --timeout)")
+	ScanCmd.Flags().Bool("privileged", false, "Send ICMP discovery probes over raw sockets (requires root)")
+	ScanCmd.Flags().String("source-ip", "", "Local address to send the probes from")
+	ScanCmd.Flags().StringP("iface", "I", "", "Network interface to send the probes from (Linux only)")
+	ScanCmd.Flags().Int("source-port", 0, "Local port to send the probes from")
+	ScanCmd.Flags().Bool("syn", false, "Half-open SYN scan on a raw socket (requires root or CAP_NET_RAW)")
+	ScanCmd.Flags().String("proxy", "", "Scan through a proxy (socks5://host:port or http://host:port)")
+	ScanCmd.Flags().Bool("all-addresses", false, "Scan every resolved address of a host separately instead of the first one")
+	ScanCmd.Flags().Int("retries", 0, "Retry probes without answer (filtered, open|filtered) up to N times")
+	ScanCmd.Flags().Bool("adaptive-timeout", false, "Derive per-host timeouts from measured round trip times, --timeout is the initial and maximum timeout")
+	ScanCmd.Flags().Bool("compare-last", false, "Report the changes since the last saved scan run on stderr, exits non-zero if there are any")
+	ScanCmd.Flags().StringP("filter-state", "s", "", "Filter scanned results by port state (open, closed, filtered, unreachable, error)")
+	ScanCmd.Flags().IntP("concurrency", "c", 500, "Maximum number of probes running at the same time across all hosts")
+	ScanCmd.Flags().Int("host-concurrency", 100, "Maximum number of probes running at the same time per host")
+	ScanCmd.Flags().BoolP("banner", "b", false, "Grab the banner of open TCP ports")
+	ScanCmd.Flags().BoolP("service-detect", "V", false, "Detect service, product and version of open TCP ports")
+	ScanCmd.Flags().String("service-db", "", "File with additional service probes and matches")
 
 	viper.BindPFlag("scan.ports", ScanCmd.Flags().Lookup("ports"))
 	viper.BindPFlag("scan.port-range", ScanCmd.Flags().Lookup("port-range"))
+	viper.BindPFlag("scan.top-ports", ScanCmd.Flags().Lookup("top-ports"))
+	viper.BindPFlag("scan.preset", ScanCmd.Flags().Lookup("preset"))
 	viper.BindPFlag("scan.network", ScanCmd.Flags().Lookup("network"))
 	viper.BindPFlag("scan.timeout", ScanCmd.Flags().Lookup("timeout"))
+	viper.BindPFlag("scan.skip-discovery", ScanCmd.Flags().Lookup("skip-discovery"))
+	viper.BindPFlag("scan.discovery", ScanCmd.Flags().Lookup("discovery"))
+	viper.BindPFlag("scan.discovery-ports", ScanCmd.Flags().Lookup("discovery-ports"))
+	viper.BindPFlag("scan.discovery-timeout", ScanCmd.Flags().Lookup("discovery-timeout"))
+	viper.BindPFlag("scan.privileged", ScanCmd.Flags().Lookup("privileged"))
+	viper.BindPFlag("scan.source-ip", ScanCmd.Flags().Lookup("source-ip"))
+	viper.BindPFlag("scan.iface", ScanCmd.Flags().Lookup("iface"))
+	viper.BindPFlag("scan.source-port", ScanCmd.Flags().Lookup("source-port"))
+	viper.BindPFlag("scan.syn", ScanCmd.Flags().Lookup("syn"))
+	viper.BindPFlag("scan.proxy", ScanCmd.Flags().Lookup("proxy"))
+	viper.BindPFlag("scan.all-addresses", ScanCmd.Flags().Lookup("all-addresses"))
+	viper.BindPFlag("scan.retries", ScanCmd.Flags().Lookup("retries"))
+	viper.BindPFlag("scan.adaptive-timeout", ScanCmd.Flags().Lookup("adaptive-timeout"))
+	viper.BindPFlag("scan.compare-last", ScanCmd.Flags().Lookup("compare-last"))
 	viper.BindPFlag("scan.filter-state", ScanCmd.Flags().Lookup("filter-state"))
+	viper.BindPFlag("scan.concurrency", ScanCmd.Flags().Lookup("concurrency"))
+	viper.BindPFlag("scan.host-concurrency", ScanCmd.Flags().Lookup("host-concurrency"))
+	viper.BindPFlag("scan.banner", ScanCmd.Flags().Lookup("banner"))
+	viper.BindPFlag("scan.service-detect", ScanCmd.Flags().Lookup("service-detect"))
+	viper.BindPFlag("scan.service-db", ScanCmd.Flags().Lookup("service-db"))
 }
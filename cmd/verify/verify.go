@@ -0,0 +1,115 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package verify
+
+import (
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/soner3/net-scan/scan"
+	"github.com/soner3/net-scan/util"
+	"github.com/soner3/net-scan/verify/action"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// verifyCmd represents the verify command
+var VerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the ports of hosts against an expected state policy",
+	Long: `The verify command scans the hosts of a policy file and checks that the
+ports which must be open are open and the ports which must be closed are
+closed or filtered, e.g. to verify firewall rules continuously. Unreachable
+hosts, failed probes and open|filtered UDP ports fail both.
+
+The policy is a YAML file with named groups of hosts (host file entries like
+hosts, CIDR blocks and ranges) and rules. Every rule names hosts or groups and
+the port expressions (like scan --ports, as a string or a list) which must be
+open and closed:
+
+  groups:
+    web: [10.0.0.10, 10.0.0.11]
+    db: [10.0.1.0/28]
+  rules:
+    - name: web servers
+      hosts: [web]
+      open: [80, 443]
+      closed: 22,3306
+    - name: databases
+      hosts: [db, 10.0.2.5]
+      open: postgresql
+      closed: -1024,U:161
+
+Every port of a host is one check. Hosts which can't be resolved fail a check
+of their own. The report is written as text, json, junit or sarif (-o) and the
+command exits with a non-zero code if any check failed.
+
+Example usage:
+  net-scan verify --policy policy.yaml
+  net-scan verify --policy policy.yaml -o junit > verify.xml
+  net-scan verify --policy policy.yaml -t 2s --retries 1`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := scan.NewOptions(
+			viper.GetString("verify.network"),
+			viper.GetDuration("verify.timeout"),
+			viper.GetInt("verify.concurrency"),
+			viper.GetInt("verify.host-concurrency"),
+		)
+		opts.Retries = viper.GetInt("verify.retries")
+		limiter, err := util.NewLimiter(viper.GetFloat64("max-rate"), viper.GetDuration("host-delay"), viper.GetDuration("jitter"))
+		if err != nil {
+			return err
+		}
+		opts.Limiter = limiter
+
+		cfg := action.NewConfig(viper.GetString("verify.policy"), viper.GetString("output"), opts)
+		progress, err := util.NewProgress(os.Stderr, viper.GetString("progress"), viper.GetDuration("progress-interval"))
+		if err != nil {
+			return err
+		}
+		cfg.Progress = progress
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		return action.VerifyAction(ctx, os.Stdout, cfg)
+	},
+}
+
+func init() {
+	VerifyCmd.SetErrPrefix("Verify Error:\n\t")
+
+	VerifyCmd.Flags().StringP("policy", "P", "", "Policy file with the expected port states (YAML)")
+	VerifyCmd.Flags().StringP("network", "n", "tcp", "Network protocol to use (tcp, tcp4, tcp6), U: ports use the matching udp network")
+	VerifyCmd.Flags().DurationP("timeout", "t", time.Millisecond*1000, "Timeout per port")
+	VerifyCmd.Flags().Int("retries", 0, "Retry probes without answer (filtered, open|filtered) up to N times")
+	VerifyCmd.Flags().IntP("concurrency", "c", 500, "Maximum number of probes running at the same time across all hosts")
+	VerifyCmd.Flags().Int("host-concurrency", 100, "Maximum number of probes running at the same time per host")
+
+	viper.BindPFlag("verify.policy", VerifyCmd.Flags().Lookup("policy"))
+	viper.BindPFlag("verify.network", VerifyCmd.Flags().Lookup("network"))
+	viper.BindPFlag("verify.timeout", VerifyCmd.Flags().Lookup("timeout"))
+	viper.BindPFlag("verify.retries", VerifyCmd.Flags().Lookup("retries"))
+	viper.BindPFlag("verify.concurrency", VerifyCmd.Flags().Lookup("concurrency"))
+	viper.BindPFlag("verify.host-concurrency", VerifyCmd.Flags().Lookup("host-concurrency"))
+}
@@ -22,25 +22,113 @@ THE SOFTWARE.
 package action
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"net"
+	"strconv"
+	"time"
 
 	"github.com/soner3/net-scan/dns"
 	"github.com/soner3/net-scan/host"
+	"github.com/soner3/net-scan/util"
 )
 
-func DnsAction(out io.Writer, filename string) error {
+var outputs = []string{util.OUTPUT_TEXT, util.OUTPUT_JSON, util.OUTPUT_JSONL, util.OUTPUT_CSV, util.OUTPUT_TSV}
+
+type Config struct {
+	Filename string
+	Output   string
+
+	// Progress reports finished hosts, nil disables it
+	Progress *util.Progress
+	// Limiter paces the queries, nil disables it
+	Limiter *util.Limiter
+	// OnResult is called with the records of every finished host, e.g. to
+	// record the run
+	OnResult func(res *dns.DnsResult)
+}
+
+func DnsAction(ctx context.Context, out io.Writer, cfg *Config) error {
+	if err := util.ValidateOutput(cfg.Output, outputs...); err != nil {
+		return err
+	}
+
 	hl := host.NewHostList()
-	if err := hl.Load(filename); err != nil {
+	if err := hl.Load(cfg.Filename); err != nil {
+		return err
+	}
+
+	out = cfg.Progress.Writer(out)
+	cfg.Progress.Start(hl.TargetCount(), "hosts", "resolved")
+	result := []dns.DnsResult{}
+	err := dns.Stream(ctx, hl.Targets(), cfg.Limiter, func(res *dns.DnsResult) {
+		result = append(result, *res)
+		if cfg.OnResult != nil {
+			cfg.OnResult(res)
+		}
+		if res.NotFound {
+			cfg.Progress.Add(1, 0)
+		} else {
+			cfg.Progress.Add(1, 1)
+		}
+	})
+	cfg.Progress.Stop()
+	if err != nil && !errors.Is(err, context.Canceled) {
 		return err
 	}
 
-	result := dns.Run(hl)
+	switch cfg.Output {
+	case util.OUTPUT_JSON, util.OUTPUT_JSONL:
+		return util.WriteJSON(out, cfg.Output, result)
+	case util.OUTPUT_CSV, util.OUTPUT_TSV:
+		return util.WriteTable(out, cfg.Output, tableHeader, tableRows(result))
+	default:
+		return writeText(out, result)
+	}
+}
+
+var tableHeader = []string{"host", "not_found", "timestamp", "type", "value", "pref"}
 
+// One row per host and record. Hosts which were not found get a single row.
+func tableRows(result []dns.DnsResult) [][]string {
+	rows := [][]string{}
+	for _, res := range result {
+		ts := res.Timestamp.Format(time.RFC3339)
+		row := func(recordType, value, pref string) {
+			rows = append(rows, []string{res.Host, strconv.FormatBool(res.NotFound), ts, recordType, value, pref})
+		}
+		if res.NotFound {
+			row("", "", "")
+			continue
+		}
+
+		row("CNAME", res.CNAME, "")
+		ipv4List, ipv6List := res.SplitIPs()
+		for _, ip := range ipv4List {
+			row("A", ip.String(), "")
+		}
+		for _, ip := range ipv6List {
+			row("AAAA", ip.String(), "")
+		}
+		for _, mx := range res.MX {
+			row("MX", mx.Host, strconv.Itoa(int(mx.Pref)))
+		}
+		for _, txt := range res.TXT {
+			row("TXT", txt, "")
+		}
+		for _, ns := range res.NS {
+			row("NS", ns.Host, "")
+		}
+	}
+	return rows
+}
+
+// Print the results in the human readable format
+func writeText(out io.Writer, result []dns.DnsResult) error {
 	output := ""
 
-	for _, res := range *result {
+	for _, res := range result {
 		output += fmt.Sprintf("%s\n", res.Host)
 		if res.NotFound {
 			output += "\tNot Found\n"
@@ -50,16 +138,7 @@ func DnsAction(out io.Writer, filename string) error {
 		output += fmt.Sprintf("\tCNAME\t%s\n", res.CNAME)
 
 		if res.IPs != nil {
-			ipv4List := make([]net.IP, 0)
-			ipv6List := make([]net.IP, 0)
-			for _, ip := range *res.IPs {
-				if ip.To4() != nil {
-					ipv4List = append(ipv4List, ip)
-				} else {
-					ipv6List = append(ipv6List, ip)
-				}
-
-			}
+			ipv4List, ipv6List := res.SplitIPs()
 
 			for _, ipv4 := range ipv4List {
 				output += fmt.Sprintf("\tA\t%s\n", ipv4)
@@ -91,7 +170,6 @@ func DnsAction(out io.Writer, filename string) error {
 		output += "\n"
 	}
 
-	fmt.Fprint(out, output)
-
-	return nil
+	_, err := fmt.Fprint(out, output)
+	return err
 }
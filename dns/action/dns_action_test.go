@@ -0,0 +1,96 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package action
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/soner3/net-scan/dns"
+	"github.com/soner3/net-scan/util"
+)
+
+func TestDnsTable(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	ips := []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")}
+	result := []dns.DnsResult{
+		{
+			Host:      "example.com",
+			CNAME:     "example.com.",
+			IPs:       &ips,
+			MX:        []*net.MX{{Host: "mail.example.com.", Pref: 10}},
+			NS:        []*net.NS{{Host: "ns1.example.com."}},
+			TXT:       []string{`v=spf1 include:"spf.example.com", -all`, "tab\tseparated\nlines"},
+			Timestamp: ts,
+		},
+		{Host: "unknown", NotFound: true, Timestamp: ts},
+	}
+
+	testCases := []struct {
+		output string
+		comma  rune
+	}{
+		{util.OUTPUT_CSV, ','},
+		{util.OUTPUT_TSV, '\t'},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.output, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := util.WriteTable(&out, tc.output, tableHeader, tableRows(result)); err != nil {
+				t.Fatal(err)
+			}
+
+			r := csv.NewReader(&out)
+			r.Comma = tc.comma
+			rows, err := r.ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stamp := ts.Format(time.RFC3339)
+			expected := [][]string{
+				tableHeader,
+				{"example.com", "false", stamp, "CNAME", "example.com.", ""},
+				{"example.com", "false", stamp, "A", "192.0.2.1", ""},
+				{"example.com", "false", stamp, "AAAA", "2001:db8::1", ""},
+				{"example.com", "false", stamp, "MX", "mail.example.com.", "10"},
+				{"example.com", "false", stamp, "TXT", `v=spf1 include:"spf.example.com", -all`, ""},
+				{"example.com", "false", stamp, "TXT", "tab\tseparated\nlines", ""},
+				{"example.com", "false", stamp, "NS", "ns1.example.com.", ""},
+				{"unknown", "true", stamp, "", "", ""},
+			}
+			if len(rows) != len(expected) {
+				t.Fatalf("Expected %d rows, got %d instead", len(expected), len(rows))
+			}
+			for i := range expected {
+				if !slices.Equal(rows[i], expected[i]) {
+					t.Errorf("Expected %q, got %q instead", expected[i], rows[i])
+				}
+			}
+		})
+	}
+}
@@ -22,70 +22,183 @@ THE SOFTWARE.
 package dns
 
 import (
+	"context"
+	"encoding/json"
+	"iter"
 	"net"
+	"time"
 
-	"github.com/soner3/net-scan/host"
+	"github.com/soner3/net-scan/util"
 )
 
 type DnsResult struct {
-	Host     string
-	IPs      *[]net.IP
-	CNAME    string
-	MX       []*net.MX
-	NS       []*net.NS
-	TXT      []string
-	NotFound bool
+	Host      string
+	IPs       *[]net.IP
+	CNAME     string
+	MX        []*net.MX
+	NS        []*net.NS
+	TXT       []string
+	NotFound  bool
+	Timestamp time.Time
 }
 
-func lookupDns(host string) DnsResult {
-	res := DnsResult{Host: host}
+type mxRecord struct {
+	Host string `json:"host"`
+	Pref uint16 `json:"pref"`
+}
+
+type dnsRecord struct {
+	Host      string     `json:"host"`
+	NotFound  bool       `json:"not_found"`
+	Timestamp time.Time  `json:"timestamp"`
+	CNAME     string     `json:"cname,omitempty"`
+	A         []string   `json:"a"`
+	AAAA      []string   `json:"aaaa"`
+	MX        []mxRecord `json:"mx"`
+	NS        []string   `json:"ns"`
+	TXT       []string   `json:"txt"`
+}
+
+// Split the looked up addresses into IPv4 and IPv6 addresses
+func (res *DnsResult) SplitIPs() ([]net.IP, []net.IP) {
+	ipv4List := make([]net.IP, 0)
+	ipv6List := make([]net.IP, 0)
+	if res.IPs == nil {
+		return ipv4List, ipv6List
+	}
+	for _, ip := range *res.IPs {
+		if ip.To4() != nil {
+			ipv4List = append(ipv4List, ip)
+		} else {
+			ipv6List = append(ipv6List, ip)
+		}
+	}
+	return ipv4List, ipv6List
+}
+
+// Marshal the result with one list per record type
+func (res DnsResult) MarshalJSON() ([]byte, error) {
+	rec := dnsRecord{
+		Host:      res.Host,
+		NotFound:  res.NotFound,
+		Timestamp: res.Timestamp,
+		CNAME:     res.CNAME,
+		A:         []string{},
+		AAAA:      []string{},
+		MX:        []mxRecord{},
+		NS:        []string{},
+		TXT:       []string{},
+	}
+
+	ipv4List, ipv6List := res.SplitIPs()
+	for _, ip := range ipv4List {
+		rec.A = append(rec.A, ip.String())
+	}
+	for _, ip := range ipv6List {
+		rec.AAAA = append(rec.AAAA, ip.String())
+	}
+	for _, mx := range res.MX {
+		rec.MX = append(rec.MX, mxRecord{Host: mx.Host, Pref: mx.Pref})
+	}
+	for _, ns := range res.NS {
+		rec.NS = append(rec.NS, ns.Host)
+	}
+	rec.TXT = append(rec.TXT, res.TXT...)
+
+	return json.Marshal(rec)
+}
+
+// Look up all records of a host. Every query waits for the limiter. Once
+// the context is canceled the host is given up and the context error is
+// returned, a failed lookup says nothing about the host then.
+func lookupDns(ctx context.Context, host string, limiter *util.Limiter) (DnsResult, error) {
+	r := net.DefaultResolver
+	res := DnsResult{Host: host, Timestamp: time.Now()}
 
-	cn, err := net.LookupCNAME(host)
+	if err := limiter.Wait(ctx, host); err != nil {
+		return res, err
+	}
+	cn, err := r.LookupCNAME(ctx, host)
 	if err != nil {
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
 		res.NotFound = true
-		return res
+		return res, nil
 	} else {
 		res.CNAME = cn
 	}
 
-	mxs, err := net.LookupMX(host)
+	if err := limiter.Wait(ctx, host); err != nil {
+		return res, err
+	}
+	mxs, err := r.LookupMX(ctx, host)
 	if err != nil {
 		res.MX = nil
 	} else {
 		res.MX = mxs
 	}
 
-	nss, err := net.LookupNS(host)
+	if err := limiter.Wait(ctx, host); err != nil {
+		return res, err
+	}
+	nss, err := r.LookupNS(ctx, host)
 	if err != nil {
 		res.NS = nil
 	} else {
 		res.NS = nss
 	}
 
-	txts, err := net.LookupTXT(host)
+	if err := limiter.Wait(ctx, host); err != nil {
+		return res, err
+	}
+	txts, err := r.LookupTXT(ctx, host)
 	if err != nil {
 		res.TXT = nil
 	} else {
 		res.TXT = txts
 	}
 
-	ips, err := net.LookupIP(host)
+	if err := limiter.Wait(ctx, host); err != nil {
+		return res, err
+	}
+	ips, err := r.LookupIP(ctx, "ip", host)
 	if err != nil {
 		res.IPs = nil
 	} else {
 		res.IPs = &ips
 	}
 
-	return res
+	// Lookups which failed because of the cancellation left records out
+	return res, ctx.Err()
 }
 
-func Run(hl *host.HostList) *[]DnsResult {
-	results := make([]DnsResult, len(hl.Hosts))
+// Look up all targets one after another. Once the context is canceled the
+// remaining targets are skipped and the context error is returned together
+// with the results so far.
+func Run(ctx context.Context, targets iter.Seq[string], limiter *util.Limiter) ([]DnsResult, error) {
+	results := []DnsResult{}
+	err := Stream(ctx, targets, limiter, func(res *DnsResult) {
+		results = append(results, *res)
+	})
+	return results, err
+}
 
-	for i, h := range hl.Hosts {
-		results[i] = lookupDns(h)
+// Stream looks up all targets one after another and hands every result to
+// onResult instead of keeping it. Once the context is canceled no further
+// targets are looked up, the host in flight is dropped and the context error
+// is returned.
+func Stream(ctx context.Context, targets iter.Seq[string], limiter *util.Limiter, onResult func(res *DnsResult)) error {
+	for h := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		res, err := lookupDns(ctx, h, limiter)
+		if err != nil {
+			return err
+		}
+		onResult(&res)
 	}
 
-	return &results
-
+	return ctx.Err()
 }
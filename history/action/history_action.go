@@ -0,0 +1,178 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package action
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/soner3/net-scan/history"
+	"github.com/soner3/net-scan/util"
+)
+
+const DELETE_MSG = "Deleted run:"
+
+var ErrValue = errors.New("invalid value")
+
+var listOutputs = []string{util.OUTPUT_TEXT, util.OUTPUT_JSON, util.OUTPUT_JSONL, util.OUTPUT_CSV, util.OUTPUT_TSV}
+
+var showOutputs = []string{util.OUTPUT_TEXT, util.OUTPUT_JSON, util.OUTPUT_JSONL}
+
+// Summary of a run in listings, without its configuration and results
+type summary struct {
+	ID        string    `json:"id"`
+	Command   string    `json:"command"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Targets   []string  `json:"targets"`
+	Results   int       `json:"results"`
+}
+
+func newSummary(run *history.Run) summary {
+	return summary{
+		ID:        run.ID,
+		Command:   run.Command,
+		StartTime: run.StartTime,
+		EndTime:   run.EndTime,
+		Targets:   run.Targets,
+		Results:   len(run.Hosts()),
+	}
+}
+
+var tableHeader = []string{"id", "command", "start_time", "end_time", "targets", "results"}
+
+// List the runs of the store, oldest first. An empty command or host
+// lists the runs of all commands or hosts.
+func ListAction(out io.Writer, store *history.Store, command string, host string, output string) error {
+	if err := util.ValidateOutput(output, listOutputs...); err != nil {
+		return err
+	}
+	runs, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	summaries := []summary{}
+	for _, run := range runs {
+		if (command == "" || run.Command == command) && (host == "" || run.Has(host)) {
+			summaries = append(summaries, newSummary(run))
+		}
+	}
+
+	switch output {
+	case util.OUTPUT_JSON, util.OUTPUT_JSONL:
+		return util.WriteJSON(out, output, summaries)
+	case util.OUTPUT_CSV, util.OUTPUT_TSV:
+		rows := make([][]string, len(summaries))
+		for i, s := range summaries {
+			rows[i] = []string{
+				s.ID, s.Command, s.StartTime.Format(time.RFC3339), s.EndTime.Format(time.RFC3339),
+				strings.Join(s.Targets, " "), strconv.Itoa(s.Results),
+			}
+		}
+		return util.WriteTable(out, output, tableHeader, rows)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCOMMAND\tSTARTED\tDURATION\tRESULTS\tTARGETS")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n",
+			s.ID, s.Command, s.StartTime.Local().Format(time.DateTime), s.EndTime.Sub(s.StartTime).Round(time.Millisecond),
+			s.Results, strings.Join(s.Targets, " "))
+	}
+	return w.Flush()
+}
+
+// Show a run with its configuration and results
+func ShowAction(out io.Writer, store *history.Store, id string, output string) error {
+	if err := util.ValidateOutput(output, showOutputs...); err != nil {
+		return err
+	}
+	run, err := store.Load(id)
+	if err != nil {
+		return err
+	}
+	if output != util.OUTPUT_TEXT {
+		return util.WriteJSON(out, output, []*history.Run{run})
+	}
+
+	var results bytes.Buffer
+	if err := json.Indent(&results, run.Results, "\t", "  "); err != nil {
+		return err
+	}
+	text := fmt.Sprintf("id:       %s\n", run.ID)
+	text += fmt.Sprintf("command:  %s\n", run.Command)
+	text += fmt.Sprintf("args:     %s\n", strings.Join(run.Args, " "))
+	text += fmt.Sprintf("started:  %s\n", run.StartTime.Format(time.RFC3339))
+	text += fmt.Sprintf("duration: %s\n", run.EndTime.Sub(run.StartTime).Round(time.Millisecond))
+	if run.Partial {
+		text += "partial:  interrupted before all hosts were done\n"
+	}
+	text += fmt.Sprintf("targets:  %s\n", strings.Join(run.Targets, " "))
+	text += "config:\n"
+	for _, key := range slices.Sorted(maps.Keys(run.Config)) {
+		text += fmt.Sprintf("\t%s: %v\n", key, run.Config[key])
+	}
+	text += fmt.Sprintf("results:\n\t%s\n", results.String())
+	_, err = fmt.Fprint(out, text)
+	return err
+}
+
+// Delete the runs with the given IDs and all runs which started more than
+// olderThan ago, 0 keeps them
+func DeleteAction(out io.Writer, store *history.Store, ids []string, olderThan time.Duration) error {
+	if len(ids) == 0 && olderThan <= 0 {
+		return fmt.Errorf("%w: either run ids or --older-than must be set", ErrValue)
+	}
+
+	if olderThan > 0 {
+		runs, err := store.List()
+		if err != nil {
+			return err
+		}
+		for _, run := range runs {
+			if time.Since(run.StartTime) > olderThan && !slices.Contains(ids, run.ID) {
+				ids = append(ids, run.ID)
+			}
+		}
+	}
+
+	for _, id := range ids {
+		deleted, err := store.Delete(id)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(out, DELETE_MSG, deleted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
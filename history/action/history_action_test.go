@@ -0,0 +1,170 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package action_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soner3/net-scan/history"
+	"github.com/soner3/net-scan/history/action"
+	"github.com/soner3/net-scan/util"
+)
+
+// Save a scan run of host1 a week ago and a dns run of host2 now
+func setup(t *testing.T) *history.Store {
+	store := history.NewStore(t.TempDir())
+	runs := []*history.Run{
+		{
+			ID:        "20250101-100000.000000",
+			Command:   "scan",
+			Config:    map[string]any{"ports": "22,80"},
+			Targets:   []string{"host1"},
+			StartTime: time.Now().Add(-7 * 24 * time.Hour),
+			EndTime:   time.Now().Add(-7 * 24 * time.Hour),
+			Results:   []byte(`[{"host":"host1"}]`),
+		},
+		{
+			ID:        "20250108-100000.000000",
+			Command:   "dns",
+			Targets:   []string{"host2"},
+			StartTime: time.Now(),
+			EndTime:   time.Now(),
+			Results:   []byte(`[{"host":"host2"}]`),
+		},
+	}
+	for _, run := range runs {
+		if err := store.Save(run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func TestListAction(t *testing.T) {
+	testData := []struct {
+		name        string
+		command     string
+		host        string
+		expectedIDs []string
+	}{
+		{"All", "", "", []string{"20250101-100000.000000", "20250108-100000.000000"}},
+		{"Command", "dns", "", []string{"20250108-100000.000000"}},
+		{"Host", "", "host1", []string{"20250101-100000.000000"}},
+		{"NoMatch", "scan", "host2", []string{}},
+	}
+
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := action.ListAction(&out, setup(t), td.command, td.host, util.OUTPUT_JSON); err != nil {
+				t.Fatal(err)
+			}
+
+			var summaries []struct {
+				ID string `json:"id"`
+			}
+			if err := json.Unmarshal(out.Bytes(), &summaries); err != nil {
+				t.Fatal(err)
+			}
+			ids := []string{}
+			for _, s := range summaries {
+				ids = append(ids, s.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(td.expectedIDs, ",") {
+				t.Errorf("Expected %v, got %v instead", td.expectedIDs, ids)
+			}
+		})
+	}
+}
+
+func TestListActionText(t *testing.T) {
+	var out bytes.Buffer
+	if err := action.ListAction(&out, setup(t), "", "", util.OUTPUT_TEXT); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "ID") {
+		t.Fatalf("Expected a header and 2 runs, got %q instead", out.String())
+	}
+	if !strings.HasPrefix(lines[1], "20250101-100000.000000  scan") {
+		t.Errorf("Expected the scan run first, got %q instead", lines[1])
+	}
+}
+
+func TestShowAction(t *testing.T) {
+	var out bytes.Buffer
+	if err := action.ShowAction(&out, setup(t), "20250101", util.OUTPUT_TEXT); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"id:       20250101-100000.000000\n", "command:  scan\n", "\tports: 22,80\n", `"host": "host1"`} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected output to contain %q, got %q instead", expected, out.String())
+		}
+	}
+
+	if err := action.ShowAction(&out, setup(t), "2025", util.OUTPUT_TEXT); !errors.Is(err, history.ErrAmbiguous) {
+		t.Errorf("Expected %q, got %q instead", history.ErrAmbiguous, err)
+	}
+}
+
+func TestDeleteAction(t *testing.T) {
+	testData := []struct {
+		name        string
+		ids         []string
+		olderThan   time.Duration
+		expectedOut string
+		expectedErr error
+	}{
+		{"ID", []string{"20250108"}, 0, "Deleted run: 20250108-100000.000000\n", nil},
+		{"OlderThan", nil, 24 * time.Hour, "Deleted run: 20250101-100000.000000\n", nil},
+		{"Both", []string{"20250108-100000.000000"}, 24 * time.Hour,
+			"Deleted run: 20250108-100000.000000\nDeleted run: 20250101-100000.000000\n", nil},
+		{"Nothing", nil, 0, "", action.ErrValue},
+		{"NotFound", []string{"2024"}, 0, "", history.ErrNotFound},
+	}
+
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := action.DeleteAction(&out, setup(t), td.ids, td.olderThan)
+			if td.expectedErr != nil {
+				if !errors.Is(err, td.expectedErr) {
+					t.Errorf("Expected %q, got %q instead", td.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != td.expectedOut {
+				t.Errorf("Expected %q, got %q instead", td.expectedOut, out.String())
+			}
+		})
+	}
+}
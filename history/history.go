@@ -0,0 +1,292 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/soner3/net-scan/util"
+)
+
+// Format of the run IDs, sortable by start time
+const ID_FORMAT = "20060102-150405.000000"
+
+var (
+	ErrNotFound  = errors.New("run not found")
+	ErrAmbiguous = errors.New("ambiguous run id")
+)
+
+// Run is one persisted run of a command with its configuration, the
+// entries of the host file and the results of all hosts. A partial run was
+// interrupted and misses the results of the hosts which were not done.
+type Run struct {
+	ID        string          `json:"id"`
+	Command   string          `json:"command"`
+	Args      []string        `json:"args"`
+	Config    map[string]any  `json:"config"`
+	Targets   []string        `json:"targets"`
+	StartTime time.Time       `json:"start_time"`
+	EndTime   time.Time       `json:"end_time"`
+	Results   json.RawMessage `json:"results"`
+	Partial   bool            `json:"partial,omitempty"`
+}
+
+// Hosts returns the host of every result. All commands name it "host".
+func (run *Run) Hosts() []string {
+	var results []struct {
+		Host string `json:"host"`
+	}
+	if err := json.Unmarshal(run.Results, &results); err != nil {
+		return nil
+	}
+	hosts := make([]string, len(results))
+	for i, res := range results {
+		hosts[i] = res.Host
+	}
+	return hosts
+}
+
+// Check if the run probed the host or has it as entry of the host file
+func (run *Run) Has(host string) bool {
+	return slices.Contains(run.Targets, host) || slices.Contains(run.Hosts(), host)
+}
+
+// Recorder collects the results of a run while the command is running. A
+// nil *Recorder records nothing.
+type Recorder struct {
+	run     Run
+	results []any
+}
+
+// NewRecorder starts recording a run. Credentials in the arguments and the
+// configuration are redacted before anything is recorded.
+func NewRecorder(command string, args []string, config map[string]any, targets []string) *Recorder {
+	var redactedConfig map[string]any
+	if config != nil {
+		redactedConfig = util.Redact(config).(map[string]any)
+	}
+	var redactedArgs []string
+	if args != nil {
+		redactedArgs = util.Redact(args).([]string)
+	}
+	return &Recorder{
+		run: Run{
+			Command:   command,
+			Args:      redactedArgs,
+			Config:    redactedConfig,
+			Targets:   targets,
+			StartTime: time.Now(),
+		},
+		results: []any{},
+	}
+}
+
+// Add the result of a finished host
+func (r *Recorder) Add(res any) {
+	if r == nil {
+		return
+	}
+	r.results = append(r.results, res)
+}
+
+// Mark the run as partial because it was interrupted
+func (r *Recorder) Interrupt() {
+	if r == nil {
+		return
+	}
+	r.run.Partial = true
+}
+
+// Save the run with all results added so far and return its ID
+func (r *Recorder) Save(store *Store) (string, error) {
+	if r == nil {
+		return "", nil
+	}
+	results, err := json.Marshal(r.results)
+	if err != nil {
+		return "", err
+	}
+	run := r.run
+	run.EndTime = time.Now()
+	run.Results = results
+	if err := store.Save(&run); err != nil {
+		return "", err
+	}
+	return run.ID, nil
+}
+
+// Store keeps every run as a JSON file named after its ID in a directory
+type Store struct {
+	Dir string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.Dir, id+".json")
+}
+
+// Save the run. Runs without ID get one from their start time, existing
+// runs are never overwritten.
+func (s *Store) Save(run *Run) error {
+	if run.ID == "" {
+		run.ID = run.StartTime.UTC().Format(ID_FORMAT)
+	}
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path(run.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// IDs of all runs, oldest first. A missing directory holds no runs.
+func (s *Store) IDs() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), ".json"); ok && !e.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// List all runs, oldest first
+func (s *Store) List() ([]*Run, error) {
+	ids, err := s.IDs()
+	if err != nil {
+		return nil, err
+	}
+	runs := []*Run{}
+	for _, id := range ids {
+		run, err := s.read(id)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+func (s *Store) read(id string) (*Run, error) {
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		return nil, err
+	}
+	run := &Run{}
+	if err := json.Unmarshal(data, run); err != nil {
+		return nil, fmt.Errorf("run %s: %w", id, err)
+	}
+	return run, nil
+}
+
+// Last returns the most recent complete run of the command, partial runs
+// are no baseline to compare with
+func (s *Store) Last(command string) (*Run, error) {
+	ids, err := s.IDs()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range slices.Backward(ids) {
+		run, err := s.read(id)
+		if err != nil {
+			return nil, err
+		}
+		if run.Command == command && !run.Partial {
+			return run, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no complete %s run", ErrNotFound, command)
+}
+
+// Resolve an ID or an unambiguous prefix of it, e.g. the date of the run
+func (s *Store) resolve(id string) (string, error) {
+	ids, err := s.IDs()
+	if err != nil {
+		return "", err
+	}
+	if slices.Contains(ids, id) {
+		return id, nil
+	}
+	matches := []string{}
+	for _, candidate := range ids {
+		if id != "" && strings.HasPrefix(candidate, id) {
+			matches = append(matches, candidate)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("%w: %s", ErrNotFound, id)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("%w: %s matches %s", ErrAmbiguous, id, strings.Join(matches, ", "))
+	}
+}
+
+// Load the run with the ID or an unambiguous prefix of it
+func (s *Store) Load(id string) (*Run, error) {
+	id, err := s.resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.read(id)
+}
+
+// Delete the run with the ID or an unambiguous prefix of it and return
+// the full ID
+func (s *Store) Delete(id string) (string, error) {
+	id, err := s.resolve(id)
+	if err != nil {
+		return "", err
+	}
+	return id, os.Remove(s.path(id))
+}
@@ -25,6 +25,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"os"
 
 	"github.com/soner3/net-scan/host"
 	"github.com/soner3/net-scan/util"
@@ -40,7 +41,7 @@ func AddAction(out io.Writer, filename string, args []string, input io.Reader) e
 		return err
 	}
 
-	piped, err := util.IsPiped()
+	piped, err := util.IsPiped(os.Stdin)
 	if err != nil {
 		return err
 	}
@@ -72,7 +73,7 @@ func DeleteAction(out io.Writer, filename string, args []string, input io.Reader
 		return err
 	}
 
-	piped, err := util.IsPiped()
+	piped, err := util.IsPiped(os.Stdin)
 	if err != nil {
 		return err
 	}
@@ -25,6 +25,7 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"iter"
 	"os"
 	"slices"
 )
@@ -47,6 +48,9 @@ func (hl *HostList) Add(host string) error {
 	if found, _ := hl.search(host); found {
 		return fmt.Errorf("%w: %s", ErrExists, host)
 	}
+	if _, err := ParseTarget(host); err != nil {
+		return err
+	}
 	hl.Hosts = append(hl.Hosts, host)
 
 	return nil
@@ -78,12 +82,32 @@ func (hl *HostList) Load(filepath string) error {
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
 		host := scanner.Text()
-		hl.Add(host)
+		if err := hl.Add(host); err != nil && !errors.Is(err, ErrExists) {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// Targets returns an iterator over all hosts of the list with CIDR blocks
+// and ranges expanded on the fly
+func (hl *HostList) Targets() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for _, entry := range hl.Hosts {
+			targets, err := ParseTarget(entry)
+			if err != nil {
+				continue
+			}
+			for t := range targets {
+				if !yield(t) {
+					return
+				}
+			}
+		}
+	}
+}
+
 // Save hosts to file
 func (hl *HostList) Save(file string) error {
 	output := fmt.Sprint(hl)
@@ -109,3 +133,12 @@ func (hl *HostList) String() string {
 	}
 	return output
 }
+
+// TargetCount returns the number of hosts Targets yields
+func (hl *HostList) TargetCount() int {
+	count := 0
+	for range hl.Targets() {
+		count++
+	}
+	return count
+}
@@ -39,6 +39,9 @@ func TestAdd(t *testing.T) {
 	}{
 		{"AddNew", "host2", nil, 2},
 		{"AddExisting", "host1", host.ErrExists, 1},
+		{"AddCIDR", "10.0.0.0/24", nil, 2},
+		{"AddInvalidCIDR", "10.0.0.0/33", host.ErrInvalidTarget, 1},
+		{"AddTooLarge", "10.0.0.0/8", host.ErrTooLarge, 1},
 	}
 
 	for _, td := range testData {
@@ -52,7 +55,7 @@ func TestAdd(t *testing.T) {
 			out := hl.Add(td.host)
 
 			if td.expectedErr != nil {
-				if !errors.Is(out, host.ErrExists) {
+				if !errors.Is(out, td.expectedErr) {
 					t.Errorf("Expected %q, got %q instead", td.expectedErr, out)
 				}
 
@@ -186,3 +189,20 @@ func TestLoadNoFile(t *testing.T) {
 	}
 
 }
+
+func TestLoadInvalidTarget(t *testing.T) {
+	tf, err := os.CreateTemp(".", "load-invalid-test*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		tf.Close()
+		os.Remove(tf.Name())
+	})
+	tf.WriteString("host1\n10.0.0.0/40\n")
+
+	hl := host.NewHostList()
+	if err := hl.Load(tf.Name()); !errors.Is(err, host.ErrInvalidTarget) {
+		t.Errorf("Expected %q, got %q instead", host.ErrInvalidTarget, err)
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package host
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Largest number of hosts a single CIDR block or range may expand to
+const MaxTargets = 1 << 16
+
+var (
+	ErrInvalidTarget = errors.New("invalid target")
+	ErrTooLarge      = errors.New("target expands to too many hosts")
+)
+
+// ParseTarget validates an entry of the host list and returns an iterator
+// over every host it describes. Besides hostnames and single addresses an
+// entry may be a CIDR block (10.0.0.0/24, 2001:db8::/120) or an IPv4
+// address with ranges or wildcards per octet (192.168.1.10-50, 10.0.*.1).
+// Nothing is expanded until the iterator is used.
+func ParseTarget(entry string) (iter.Seq[string], error) {
+	switch {
+	case strings.Contains(entry, "/"):
+		return parsePrefix(entry)
+	case isOctetRange(entry):
+		return parseOctetRange(entry)
+	default:
+		return func(yield func(string) bool) {
+			yield(entry)
+		}, nil
+	}
+}
+
+// Expand a CIDR block. The network and broadcast addresses of IPv4
+// blocks are skipped as long as the block has room for hosts.
+func parsePrefix(entry string) (iter.Seq[string], error) {
+	prefix, err := netip.ParsePrefix(entry)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTarget, err)
+	}
+	prefix = prefix.Masked()
+
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > 16 {
+		return nil, fmt.Errorf("%w: %s exceeds %d hosts", ErrTooLarge, entry, MaxTargets)
+	}
+	skipEdges := prefix.Addr().Is4() && hostBits > 1
+
+	return func(yield func(string) bool) {
+		first := prefix.Addr()
+		for addr := first; addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+			if skipEdges && (addr == first || !prefix.Contains(addr.Next())) {
+				continue
+			}
+			if !yield(addr.String()) {
+				return
+			}
+		}
+	}, nil
+}
+
+// Check if the entry looks like an IPv4 address with ranges or wildcards
+func isOctetRange(entry string) bool {
+	if !strings.ContainsAny(entry, "-*") || strings.Count(entry, ".") != 3 {
+		return false
+	}
+	for _, r := range entry {
+		if (r < '0' || r > '9') && r != '.' && r != '-' && r != '*' {
+			return false
+		}
+	}
+	return true
+}
+
+// Expand an IPv4 address whose octets may be a number, a range or "*"
+func parseOctetRange(entry string) (iter.Seq[string], error) {
+	var octets [4][2]int
+	size := 1
+	for i, part := range strings.Split(entry, ".") {
+		start, end, err := parseOctet(part)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %s", ErrInvalidTarget, entry, err)
+		}
+		octets[i] = [2]int{start, end}
+		size *= end - start + 1
+	}
+	if size > MaxTargets {
+		return nil, fmt.Errorf("%w: %s exceeds %d hosts", ErrTooLarge, entry, MaxTargets)
+	}
+
+	return func(yield func(string) bool) {
+		for a := octets[0][0]; a <= octets[0][1]; a++ {
+			for b := octets[1][0]; b <= octets[1][1]; b++ {
+				for c := octets[2][0]; c <= octets[2][1]; c++ {
+					for d := octets[3][0]; d <= octets[3][1]; d++ {
+						if !yield(fmt.Sprintf("%d.%d.%d.%d", a, b, c, d)) {
+							return
+						}
+					}
+				}
+			}
+		}
+	}, nil
+}
+
+// Parse a single octet of an address range
+func parseOctet(part string) (int, int, error) {
+	if part == "*" {
+		return 0, 255, nil
+	}
+
+	from, to, isRange := strings.Cut(part, "-")
+	start, err := strconv.Atoi(from)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid octet %q", part)
+	}
+	end := start
+	if isRange {
+		if end, err = strconv.Atoi(to); err != nil {
+			return 0, 0, fmt.Errorf("invalid octet %q", part)
+		}
+	}
+	if start < 0 || end > 255 || start > end {
+		return 0, 0, fmt.Errorf("octet %q out of range", part)
+	}
+	return start, end, nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package host_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/soner3/net-scan/host"
+)
+
+func TestParseTarget(t *testing.T) {
+	testCases := []struct {
+		name        string
+		entry       string
+		expectedErr error
+		expectedLen int
+		first       string
+		last        string
+	}{
+		{"Hostname", "my-host-1", nil, 1, "my-host-1", "my-host-1"},
+		{"IPv4", "10.0.0.1", nil, 1, "10.0.0.1", "10.0.0.1"},
+		{"IPv6", "2001:db8::1", nil, 1, "2001:db8::1", "2001:db8::1"},
+		{"CIDR24", "10.0.0.0/24", nil, 254, "10.0.0.1", "10.0.0.254"},
+		{"CIDRUnmasked", "10.0.0.77/30", nil, 2, "10.0.0.77", "10.0.0.78"},
+		{"CIDR31", "10.0.0.0/31", nil, 2, "10.0.0.0", "10.0.0.1"},
+		{"CIDR32", "10.0.0.5/32", nil, 1, "10.0.0.5", "10.0.0.5"},
+		{"CIDRv6", "2001:db8::/126", nil, 4, "2001:db8::", "2001:db8::3"},
+		{"Range", "192.168.1.10-50", nil, 41, "192.168.1.10", "192.168.1.50"},
+		{"Wildcard", "10.0.*.1", nil, 256, "10.0.0.1", "10.0.255.1"},
+		{"CIDRTooLarge", "10.0.0.0/8", host.ErrTooLarge, 0, "", ""},
+		{"CIDRv6TooLarge", "2001:db8::/64", host.ErrTooLarge, 0, "", ""},
+		{"WildcardTooLarge", "10.*.*.*", host.ErrTooLarge, 0, "", ""},
+		{"InvalidCIDR", "10.0.0.0/33", host.ErrInvalidTarget, 0, "", ""},
+		{"InvalidOctet", "10.0.0.300-301", host.ErrInvalidTarget, 0, "", ""},
+		{"ReversedRange", "10.0.0.50-10", host.ErrInvalidTarget, 0, "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			targets, err := host.ParseTarget(tc.entry)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected %v, got %v instead", tc.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			out := slices.Collect(targets)
+			if len(out) != tc.expectedLen {
+				t.Fatalf("Expected %d, got %d instead", tc.expectedLen, len(out))
+			}
+			if out[0] != tc.first {
+				t.Errorf("Expected %s, got %s instead", tc.first, out[0])
+			}
+			if out[len(out)-1] != tc.last {
+				t.Errorf("Expected %s, got %s instead", tc.last, out[len(out)-1])
+			}
+		})
+	}
+}
+
+func TestTargets(t *testing.T) {
+	hl := host.NewHostList()
+	for _, h := range []string{"localhost", "10.0.0.0/30", "10.0.1.1-2"} {
+		if err := hl.Add(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{"10.0.0.1", "10.0.0.2", "10.0.1.1", "10.0.1.2", "localhost"}
+	out := slices.Collect(hl.Targets())
+	slices.Sort(out)
+	if !slices.Equal(out, expected) {
+		t.Errorf("Expected %v, got %v instead", expected, out)
+	}
+}
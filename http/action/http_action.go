@@ -22,14 +22,19 @@ THE SOFTWARE.
 package action
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/soner3/net-scan/host"
 	"github.com/soner3/net-scan/http"
+	"github.com/soner3/net-scan/util"
 )
 
 type Config struct {
@@ -37,17 +42,35 @@ type Config struct {
 	CallFrequency time.Duration
 	Timeout       time.Duration
 	Secure        bool
+	Output        string
+
+	// Progress reports finished hosts, nil disables it
+	Progress *util.Progress
+	// Limiter paces the hosts and their probes, nil disables it
+	Limiter *util.Limiter
+	// Proxy connects through a SOCKS5 or HTTP CONNECT proxy, nil connects
+	// directly
+	Proxy *util.Proxy
+	// Source binds the connections to a local address, interface and port,
+	// nil uses the default route
+	Source *util.Source
+	// OnResult is called with the calls of every finished host, e.g. to
+	// record the run
+	OnResult func(res *http.Result)
 }
 
-func NewConfig(filename string, callFrequency, timeout time.Duration, secure bool) *Config {
+func NewConfig(filename string, callFrequency, timeout time.Duration, secure bool, output string) *Config {
 	return &Config{
 		Filename:      filename,
 		CallFrequency: callFrequency,
 		Timeout:       timeout,
 		Secure:        secure,
+		Output:        output,
 	}
 }
 
+var outputs = []string{util.OUTPUT_TEXT, util.OUTPUT_JSON, util.OUTPUT_JSONL, util.OUTPUT_CSV, util.OUTPUT_TSV, util.OUTPUT_JUNIT}
+
 var (
 	ErrInvalidHTTP = errors.New("invalid HTTP config")
 	ErrEmptyFile   = errors.New("host file is empty")
@@ -76,11 +99,35 @@ func (cfg *Config) validate() error {
 	if cfg.Timeout <= 0 {
 		return fmt.Errorf("%w: timeout must be > 0", ErrInvalidHTTP)
 	}
+	if err := util.ValidateOutput(cfg.Output, outputs...); err != nil {
+		return err
+	}
 
 	return nil
 }
 
-func HttpAction(out io.Writer, cfg *Config) error {
+// Print a single call in the human readable format
+func printCall(out io.Writer, call http.Call) {
+	if call.Error != "" {
+		fmt.Fprintf(out, "\t%s\n", call.Error)
+		return
+	}
+	fmt.Fprintf(out, "\tgot resp, status code: %d, latency: %s\n", call.StatusCode, call.Latency)
+}
+
+// Count a host as reachable if at least one call got a response
+func reachable(res *http.Result) int {
+	for _, call := range res.Calls {
+		if call.Error == "" {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Call all hosts one after another. Ctrl-C stops calling the current host
+// and continues with the next one, canceling the context skips all of them.
+func HttpAction(ctx context.Context, out io.Writer, cfg *Config) error {
 	if err := cfg.validate(); err != nil {
 		return err
 	}
@@ -89,10 +136,121 @@ func HttpAction(out io.Writer, cfg *Config) error {
 	if err := hl.Load(cfg.Filename); err != nil {
 		return err
 	}
-	for _, h := range hl.Hosts {
-		if err := http.Run(out, h, cfg.Secure, cfg.CallFrequency, cfg.Timeout); err != nil {
+	out = cfg.Progress.Writer(out)
+	cfg.Progress.Start(hl.TargetCount(), "hosts", "reachable")
+	defer cfg.Progress.Stop()
+
+	results := []*http.Result{}
+	for h := range hl.Targets() {
+		if ctx.Err() != nil {
+			break
+		}
+
+		httpCfg := http.NewConfig(cfg.Secure, cfg.CallFrequency, cfg.Timeout)
+		httpCfg.Limiter = cfg.Limiter
+		httpCfg.Source = cfg.Source
+		httpCfg.Proxy = cfg.Proxy
+		// Calls are only printed in the text format, all other formats
+		// are written from the collected calls
+		text := cfg.Output == util.OUTPUT_TEXT
+		if text {
+			httpCfg.OnCall = func(call http.Call) {
+				printCall(out, call)
+			}
+			fmt.Fprintf(out, "%s:\n", http.URL(h, cfg.Secure))
+		}
+
+		hostCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		res, err := http.Run(hostCtx, h, httpCfg)
+		stop()
+		if err != nil {
 			return err
 		}
+		results = append(results, res)
+		if cfg.OnResult != nil {
+			cfg.OnResult(res)
+		}
+		cfg.Progress.Add(1, reachable(res))
+
+		if text {
+			if res.NotFound {
+				fmt.Fprint(out, "\tNot Found\n")
+			}
+			fmt.Fprint(out, "\n")
+		}
+	}
+
+	switch cfg.Output {
+	case util.OUTPUT_JSON, util.OUTPUT_JSONL:
+		return util.WriteJSON(out, cfg.Output, results)
+	case util.OUTPUT_CSV, util.OUTPUT_TSV:
+		return util.WriteTable(out, cfg.Output, tableHeader, tableRows(results))
+	case util.OUTPUT_JUNIT:
+		return writeJUnit(out, results)
 	}
 	return nil
 }
+
+// One test case per host, which fails if the host was not found or a call
+// failed or got a status code of 400 or above
+func writeJUnit(out io.Writer, results []*http.Result) error {
+	suite := util.JUnitSuite{Name: "http", Cases: []util.JUnitCase{}}
+	for _, res := range results {
+		tc := util.JUnitCase{Name: res.URL, ClassName: res.Host}
+		if len(res.Calls) > 0 {
+			tc.Time = res.Calls[len(res.Calls)-1].Timestamp.Sub(res.Calls[0].Timestamp).Seconds()
+		}
+
+		failed := []string{}
+		for _, call := range res.Calls {
+			if call.Error != "" {
+				failed = append(failed, call.Error)
+			} else if call.StatusCode >= 400 {
+				failed = append(failed, fmt.Sprintf("status code %d", call.StatusCode))
+			}
+		}
+		switch {
+		case res.NotFound:
+			tc.Failure = &util.JUnitFailure{Message: "host not found", Type: "not-found"}
+		case len(res.Calls) == 0:
+			tc.Failure = &util.JUnitFailure{Message: "no calls", Type: "unreachable"}
+		case len(failed) > 0:
+			tc.Failure = &util.JUnitFailure{
+				Message: fmt.Sprintf("%d of %d calls failed: %s", len(failed), len(res.Calls), failed[0]),
+				Type:    "failed-call",
+				Text:    strings.Join(failed, "\n"),
+			}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	return util.WriteJUnit(out, &util.JUnitReport{Name: "net-scan http", Suites: []util.JUnitSuite{suite}})
+}
+
+var tableHeader = []string{"host", "url", "not_found", "timestamp", "status_code", "latency_ns", "error"}
+
+// One row per call. Hosts which were not found get a single row.
+func tableRows(results []*http.Result) [][]string {
+	rows := [][]string{}
+	for _, res := range results {
+		if res.NotFound {
+			rows = append(rows, []string{res.Host, res.URL, "true", "", "", "", ""})
+			continue
+		}
+		for _, call := range res.Calls {
+			status := ""
+			if call.StatusCode != 0 {
+				status = strconv.Itoa(call.StatusCode)
+			}
+			rows = append(rows, []string{
+				res.Host,
+				res.URL,
+				"false",
+				call.Timestamp.Format(time.RFC3339),
+				status,
+				strconv.FormatInt(int64(call.Latency), 10),
+				call.Error,
+			})
+		}
+	}
+	return rows
+}
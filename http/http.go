@@ -22,52 +22,132 @@ THE SOFTWARE.
 package http
 
 import (
+	"context"
 	"fmt"
-	"io"
 	"net"
-	"os"
-	"os/signal"
+	nethttp "net/http"
+	"sync"
 	"time"
 
 	probing "github.com/prometheus-community/pro-bing"
+	"github.com/soner3/net-scan/util"
 )
 
-func Run(out io.Writer, host string, secure bool, callFrequency, timeout time.Duration) error {
-	var url string
+// A single HTTP call. Failed calls carry the error instead of a status code.
+// The latency is in nanoseconds.
+type Call struct {
+	Timestamp  time.Time     `json:"timestamp"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Latency    time.Duration `json:"latency_ns,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// All calls made to a single host
+type Result struct {
+	Host     string `json:"host"`
+	URL      string `json:"url"`
+	NotFound bool   `json:"not_found"`
+	Calls    []Call `json:"calls"`
+
+	mu sync.Mutex
+}
+
+// Config of the periodic HTTP calls
+type Config struct {
+	Secure        bool
+	CallFrequency time.Duration
+	Timeout       time.Duration
+	// Limiter delays the first call and widens the call frequency, nil
+	// disables it
+	Limiter *util.Limiter
+	// Proxy connects through a SOCKS5 or HTTP CONNECT proxy, which also
+	// resolves the host names
+	Proxy *util.Proxy
+	// Source binds the connections to a local address, interface and port,
+	// nil uses the default route
+	Source *util.Source
+
+	// OnCall is called for every finished call, successful or not
+	OnCall func(call Call)
+}
+
+func NewConfig(secure bool, callFrequency, timeout time.Duration) *Config {
+	return &Config{
+		Secure:        secure,
+		CallFrequency: callFrequency,
+		Timeout:       timeout,
+	}
+}
+
+func (res *Result) add(cfg *Config, call Call) {
+	res.mu.Lock()
+	res.Calls = append(res.Calls, call)
+	res.mu.Unlock()
+	if cfg.OnCall != nil {
+		cfg.OnCall(call)
+	}
+}
+
+// pro-bing only reports failed calls through its logger, so the logger
+// records them as calls of the result
+type callLogger struct {
+	cfg *Config
+	res *Result
+}
+
+func (l *callLogger) Errorf(format string, v ...any) {
+	l.res.add(l.cfg, Call{Timestamp: time.Now(), Error: fmt.Sprintf(format, v...)})
+}
+
+func (l *callLogger) Fatalf(format string, v ...any) { l.Errorf(format, v...) }
+func (l *callLogger) Warnf(format string, v ...any)  {}
+func (l *callLogger) Infof(format string, v ...any)  {}
+func (l *callLogger) Debugf(format string, v ...any) {}
+
+// URL called for the host
+func URL(host string, secure bool) string {
 	if secure {
-		url = fmt.Sprintf("https://%s", host)
-	} else {
-		url = fmt.Sprintf("http://%s", host)
+		return fmt.Sprintf("https://%s", host)
+	}
+	return fmt.Sprintf("http://%s", host)
+}
+
+// Call the host periodically until the context is canceled and return all
+// calls that were made
+func Run(ctx context.Context, host string, cfg *Config) (*Result, error) {
+	url := URL(host, cfg.Secure)
+	res := &Result{Host: host, URL: url, Calls: []Call{}}
+	if err := cfg.Limiter.Wait(ctx, host); err != nil {
+		return res, nil
 	}
 
-	httpCaller := probing.NewHttpCaller(
-		url,
-		probing.WithHTTPCallerCallFrequency(callFrequency),
+	// Behind a proxy the host name may only resolve on the other side
+	if cfg.Proxy == nil {
+		if _, err := net.DefaultResolver.LookupHost(ctx, host); err != nil {
+			res.NotFound = true
+			return res, nil
+		}
+	}
+
+	options := []probing.HTTPCallerOption{
+		probing.WithHTTPCallerCallFrequency(max(cfg.CallFrequency, cfg.Limiter.MinInterval())),
 		probing.WithHTTPCallerOnResp(func(suite *probing.TraceSuite, info *probing.HTTPCallInfo) {
-			fmt.Fprintf(out, "\tgot resp, status code: %d, latency: %s\n",
-				info.StatusCode,
-				suite.GetGeneralEnd().Sub(suite.GetGeneralStart()),
-			)
+			latency := suite.GetGeneralEnd().Sub(suite.GetGeneralStart())
+			res.add(cfg, Call{Timestamp: suite.GetGeneralStart(), StatusCode: info.StatusCode, Latency: latency})
 		}),
-		probing.WithHTTPCallerTimeout(timeout),
-	)
-
-	// Listen for Ctrl-C.
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	go func() {
-		<-c
-		httpCaller.Stop()
-	}()
-
-	fmt.Fprintf(out, "%s:\n", url)
-
-	if _, err := net.LookupHost(host); err != nil {
-		fmt.Fprint(out, "\tNot Found\n\n")
-		return nil
+		probing.WithHTTPCallerTimeout(cfg.Timeout),
+		probing.WithHTTPCallerLogger(&callLogger{cfg: cfg, res: res}),
+	}
+	if cfg.Source != nil || cfg.Proxy != nil {
+		transport := nethttp.DefaultTransport.(*nethttp.Transport).Clone()
+		transport.DialContext = cfg.Source.Dialer("tcp", cfg.Timeout).DialContext
+		if cfg.Proxy != nil {
+			transport.Proxy = nethttp.ProxyURL(cfg.Proxy.URL)
+		}
+		options = append(options, probing.WithHTTPCallerClient(&nethttp.Client{Transport: transport}))
 	}
 
-	httpCaller.Run()
-	fmt.Fprintf(out, "\n")
-	return nil
+	httpCaller := probing.NewHttpCaller(url, options...)
+	httpCaller.RunWithContext(ctx)
+	return res, nil
 }
@@ -22,14 +22,18 @@ THE SOFTWARE.
 package action
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"strconv"
 	"time"
 
 	"github.com/soner3/net-scan/host"
 	"github.com/soner3/net-scan/ping"
+	"github.com/soner3/net-scan/util"
 )
 
 type Config struct {
@@ -42,9 +46,18 @@ type Config struct {
 	Iface      string
 	Tclass     int
 	Priveleged bool
+	Output     string
+
+	// Progress reports finished hosts, nil disables it
+	Progress *util.Progress
+	// Limiter paces the hosts and their probes, nil disables it
+	Limiter *util.Limiter
+	// OnResult is called with the statistics of every finished host, e.g.
+	// to record the run
+	OnResult func(res *ping.Result)
 }
 
-func NewConfig(filename string, timeout, interval time.Duration, count, size, ttl int, iface string, tclass int, privileged bool) *Config {
+func NewConfig(filename string, timeout, interval time.Duration, count, size, ttl int, iface string, tclass int, privileged bool, output string) *Config {
 	return &Config{
 		Filename:   filename,
 		Timeout:    timeout,
@@ -55,9 +68,12 @@ func NewConfig(filename string, timeout, interval time.Duration, count, size, tt
 		Iface:      iface,
 		Tclass:     tclass,
 		Priveleged: privileged,
+		Output:     output,
 	}
 }
 
+var outputs = []string{util.OUTPUT_TEXT, util.OUTPUT_JSON, util.OUTPUT_JSONL, util.OUTPUT_CSV, util.OUTPUT_TSV, util.OUTPUT_JUNIT}
+
 var (
 	ErrEmptyFile   = errors.New("host file is empty")
 	ErrInvalidPing = errors.New("invalid ping config")
@@ -98,11 +114,47 @@ func (cfg *Config) validate() error {
 	if cfg.Tclass < -1 || cfg.Tclass > 255 {
 		return fmt.Errorf("%w: tclass must be between -1 and 255", ErrInvalidPing)
 	}
+	if err := util.ValidateOutput(cfg.Output, outputs...); err != nil {
+		return err
+	}
 
 	return nil
 }
 
-func PingAction(out io.Writer, cfg *Config) error {
+// Print the statistics of a host in the human readable format
+func writeText(out io.Writer, res *ping.Result) error {
+	if res.NotFound {
+		_, err := fmt.Fprintf(out, "%s:\n\tNot Found\n\n", res.Host)
+		return err
+	}
+	_, err := fmt.Fprintf(out, "\n\t--- %s ping statistics ---\n"+
+		"\t%d packets transmitted, %d packets received, %v%% packet loss\n"+
+		"\tround-trip min/avg/max/stddev = %v/%v/%v/%v\n\n",
+		res.Host,
+		res.PacketsSent, res.PacketsRecv, res.PacketLoss,
+		res.MinRtt, res.AvgRtt, res.MaxRtt, res.StdDevRtt)
+	return err
+}
+
+// Print every reply while pinging in the text format
+func printReplies(out io.Writer, pingCfg *ping.Config) {
+	pingCfg.OnStart = func(host, addr string) {
+		fmt.Fprintf(out, "PING %s (%s):\n", host, addr)
+	}
+	pingCfg.OnReply = func(reply ping.Reply) {
+		if reply.Duplicate {
+			fmt.Fprintf(out, "\t%d bytes from %s: icmp_seq=%d time=%v ttl=%v (DUP!)\n",
+				reply.Bytes, reply.Addr, reply.Seq, reply.Rtt, reply.TTL)
+			return
+		}
+		fmt.Fprintf(out, "\t%d bytes from %s: icmp_seq=%d time=%v\n",
+			reply.Bytes, reply.Addr, reply.Seq, reply.Rtt)
+	}
+}
+
+// Ping all hosts one after another. Ctrl-C stops pinging the current host
+// and continues with the next one, canceling the context skips all of them.
+func PingAction(ctx context.Context, out io.Writer, cfg *Config) error {
 	if err := cfg.validate(); err != nil {
 		return err
 	}
@@ -111,7 +163,15 @@ func PingAction(out io.Writer, cfg *Config) error {
 	if err := hl.Load(cfg.Filename); err != nil {
 		return err
 	}
-	for _, h := range hl.Hosts {
+	out = cfg.Progress.Writer(out)
+	cfg.Progress.Start(hl.TargetCount(), "hosts", "alive")
+	defer cfg.Progress.Stop()
+
+	results := []ping.Result{}
+	for h := range hl.Targets() {
+		if ctx.Err() != nil {
+			break
+		}
 		pingCfg := ping.NewConfig(
 			cfg.Count,
 			cfg.Size,
@@ -122,9 +182,94 @@ func PingAction(out io.Writer, cfg *Config) error {
 			cfg.Priveleged,
 			cfg.Tclass,
 		)
-		if err := ping.Run(out, h, pingCfg); err != nil {
+		pingCfg.Limiter = cfg.Limiter
+		// Replies are only printed in the text format, all other formats
+		// are written from the collected statistics
+		if cfg.Output == util.OUTPUT_TEXT {
+			printReplies(out, pingCfg)
+		}
+
+		hostCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		res, err := ping.Run(hostCtx, h, pingCfg)
+		stop()
+		if err != nil && !errors.Is(err, context.Canceled) {
 			return err
 		}
+		results = append(results, *res)
+		if cfg.OnResult != nil {
+			cfg.OnResult(res)
+		}
+		if res.PacketsRecv > 0 {
+			cfg.Progress.Add(1, 1)
+		} else {
+			cfg.Progress.Add(1, 0)
+		}
+
+		if cfg.Output == util.OUTPUT_TEXT {
+			if err := writeText(out, res); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch cfg.Output {
+	case util.OUTPUT_JSON, util.OUTPUT_JSONL:
+		return util.WriteJSON(out, cfg.Output, results)
+	case util.OUTPUT_CSV, util.OUTPUT_TSV:
+		return util.WriteTable(out, cfg.Output, tableHeader, tableRows(results))
+	case util.OUTPUT_JUNIT:
+		return writeJUnit(out, results)
 	}
 	return nil
 }
+
+// One test case per host, which fails if the host was not found or no
+// reply came back
+func writeJUnit(out io.Writer, results []ping.Result) error {
+	suite := util.JUnitSuite{Name: "ping", Cases: []util.JUnitCase{}}
+	for _, res := range results {
+		tc := util.JUnitCase{Name: res.Host, ClassName: "ping", Time: res.EndTime.Sub(res.StartTime).Seconds()}
+		switch {
+		case res.NotFound:
+			tc.Failure = &util.JUnitFailure{Message: "host not found", Type: "not-found"}
+		case res.PacketsRecv == 0:
+			tc.Failure = &util.JUnitFailure{
+				Message: fmt.Sprintf("no reply to %d packets", res.PacketsSent),
+				Type:    "unreachable",
+			}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	if len(results) > 0 {
+		suite.Timestamp = results[0].StartTime.Format(time.RFC3339)
+	}
+	return util.WriteJUnit(out, &util.JUnitReport{Name: "net-scan ping", Suites: []util.JUnitSuite{suite}})
+}
+
+var tableHeader = []string{
+	"host", "addr", "not_found", "start_time", "end_time", "packets_sent", "packets_recv",
+	"packets_recv_duplicates", "packet_loss", "min_rtt_ns", "avg_rtt_ns", "max_rtt_ns", "stddev_rtt_ns",
+}
+
+// One summary row per host
+func tableRows(results []ping.Result) [][]string {
+	rows := make([][]string, 0, len(results))
+	for _, res := range results {
+		rows = append(rows, []string{
+			res.Host,
+			res.Addr,
+			strconv.FormatBool(res.NotFound),
+			res.StartTime.Format(time.RFC3339),
+			res.EndTime.Format(time.RFC3339),
+			strconv.Itoa(res.PacketsSent),
+			strconv.Itoa(res.PacketsRecv),
+			strconv.Itoa(res.PacketsRecvDuplicates),
+			strconv.FormatFloat(res.PacketLoss, 'f', -1, 64),
+			strconv.FormatInt(int64(res.MinRtt), 10),
+			strconv.FormatInt(int64(res.AvgRtt), 10),
+			strconv.FormatInt(int64(res.MaxRtt), 10),
+			strconv.FormatInt(int64(res.StdDevRtt), 10),
+		})
+	}
+	return rows
+}
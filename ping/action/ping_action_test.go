@@ -0,0 +1,92 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package action
+
+import (
+	"bytes"
+	"encoding/csv"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/soner3/net-scan/ping"
+	"github.com/soner3/net-scan/util"
+)
+
+func TestPingTable(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	end := start.Add(3 * time.Second)
+	results := []ping.Result{
+		{
+			Host:        "fe80::1%eth0",
+			Addr:        "fe80::1%eth0",
+			StartTime:   start,
+			EndTime:     end,
+			PacketsSent: 3,
+			PacketsRecv: 2,
+			PacketLoss:  100.0 / 3,
+			MinRtt:      time.Millisecond,
+			AvgRtt:      1500 * time.Microsecond,
+			MaxRtt:      2 * time.Millisecond,
+			StdDevRtt:   500 * time.Microsecond,
+		},
+		{Host: "unknown", NotFound: true, StartTime: start, EndTime: start},
+	}
+
+	testCases := []struct {
+		output string
+		comma  rune
+	}{
+		{util.OUTPUT_CSV, ','},
+		{util.OUTPUT_TSV, '\t'},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.output, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := util.WriteTable(&out, tc.output, tableHeader, tableRows(results)); err != nil {
+				t.Fatal(err)
+			}
+
+			r := csv.NewReader(&out)
+			r.Comma = tc.comma
+			rows, err := r.ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := [][]string{
+				tableHeader,
+				{"fe80::1%eth0", "fe80::1%eth0", "false", start.Format(time.RFC3339), end.Format(time.RFC3339), "3", "2", "0", "33.333333333333336", "1000000", "1500000", "2000000", "500000"},
+				{"unknown", "", "true", start.Format(time.RFC3339), start.Format(time.RFC3339), "0", "0", "0", "0", "0", "0", "0", "0"},
+			}
+			if len(rows) != len(expected) {
+				t.Fatalf("Expected %d rows, got %d instead", len(expected), len(rows))
+			}
+			for i := range expected {
+				if !slices.Equal(rows[i], expected[i]) {
+					t.Errorf("Expected %q, got %q instead", expected[i], rows[i])
+				}
+			}
+		})
+	}
+}
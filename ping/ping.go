@@ -22,14 +22,13 @@ THE SOFTWARE.
 package ping
 
 import (
+	"context"
 	"fmt"
-	"io"
 	"net"
-	"os"
-	"os/signal"
 	"time"
 
 	probing "github.com/prometheus-community/pro-bing"
+	"github.com/soner3/net-scan/util"
 )
 
 type Config struct {
@@ -41,6 +40,34 @@ type Config struct {
 	Iface      string
 	Privileged bool
 	TClass     int
+	// Limiter delays the start and widens the interval, nil disables it
+	Limiter *util.Limiter
+
+	// OnStart is called with the resolved address before the first packet is sent
+	OnStart func(host, addr string)
+	// OnReply is called for every received reply
+	OnReply func(reply Reply)
+}
+
+// A single echo reply. Duplicate replies are reported with Duplicate set.
+type Reply struct {
+	Bytes     int
+	Addr      string
+	Seq       int
+	Rtt       time.Duration
+	TTL       int
+	Duplicate bool
+}
+
+func newReply(pkt *probing.Packet, duplicate bool) Reply {
+	return Reply{
+		Bytes:     pkt.Nbytes,
+		Addr:      pkt.IPAddr.String(),
+		Seq:       pkt.Seq,
+		Rtt:       pkt.Rtt,
+		TTL:       pkt.TTL,
+		Duplicate: duplicate,
+	}
 }
 
 func NewConfig(count, size int, interval, timeout time.Duration, ttl int, iface string, privileged bool, tclass int) *Config {
@@ -56,57 +83,90 @@ func NewConfig(count, size int, interval, timeout time.Duration, ttl int, iface
 	}
 }
 
-func Run(out io.Writer, host string, cfg *Config) error {
+// Statistics of pinging a single host. Round-trip times are in nanoseconds.
+type Result struct {
+	Host                  string        `json:"host"`
+	Addr                  string        `json:"addr,omitempty"`
+	NotFound              bool          `json:"not_found"`
+	StartTime             time.Time     `json:"start_time"`
+	EndTime               time.Time     `json:"end_time"`
+	PacketsSent           int           `json:"packets_sent"`
+	PacketsRecv           int           `json:"packets_recv"`
+	PacketsRecvDuplicates int           `json:"packets_recv_duplicates"`
+	PacketLoss            float64       `json:"packet_loss"`
+	MinRtt                time.Duration `json:"min_rtt_ns"`
+	AvgRtt                time.Duration `json:"avg_rtt_ns"`
+	MaxRtt                time.Duration `json:"max_rtt_ns"`
+	StdDevRtt             time.Duration `json:"stddev_rtt_ns"`
+}
+
+func newResult(host string, stats *probing.Statistics) *Result {
+	res := &Result{
+		Host:                  host,
+		PacketsSent:           stats.PacketsSent,
+		PacketsRecv:           stats.PacketsRecv,
+		PacketsRecvDuplicates: stats.PacketsRecvDuplicates,
+		PacketLoss:            stats.PacketLoss,
+		MinRtt:                stats.MinRtt,
+		AvgRtt:                stats.AvgRtt,
+		MaxRtt:                stats.MaxRtt,
+		StdDevRtt:             stats.StdDevRtt,
+	}
+	if stats.IPAddr != nil {
+		res.Addr = stats.IPAddr.String()
+	}
+	return res
+}
+
+// Ping the host and return the statistics. Replies are reported through the
+// callbacks of the config. Canceling the context stops pinging, the
+// statistics gathered so far are returned together with the context error.
+func Run(ctx context.Context, host string, cfg *Config) (*Result, error) {
+	start := time.Now()
+	if err := cfg.Limiter.Wait(ctx, host); err != nil {
+		return &Result{Host: host, StartTime: start, EndTime: time.Now()}, err
+	}
+
 	pinger, err := probing.NewPinger(host)
 	if err != nil {
-		if _, err := net.LookupHost(host); err != nil {
-			fmt.Fprintf(out, "%s:\n\tNot Found\n\n", host)
-			return nil
+		if _, err := net.DefaultResolver.LookupHost(ctx, host); err != nil {
+			return &Result{Host: host, NotFound: true, StartTime: start, EndTime: time.Now()}, nil
 		} else {
-			return err
+			return nil, err
 		}
 	}
 
-	// Listen for Ctrl-C.
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	go func() {
-		for range c {
-			pinger.Stop()
-		}
-	}()
-
 	pinger.Count = cfg.Count
 	pinger.Size = cfg.Size
-	pinger.Interval = cfg.Interval
+	pinger.Interval = max(cfg.Interval, cfg.Limiter.MinInterval())
 	pinger.Timeout = cfg.Timeout
 	pinger.TTL = cfg.TTL
 	pinger.InterfaceName = cfg.Iface
 	pinger.SetPrivileged(cfg.Privileged)
 	pinger.SetTrafficClass(uint8(cfg.TClass))
 
-	pinger.OnRecv = func(pkt *probing.Packet) {
-		fmt.Fprintf(out, "\t%d bytes from %s: icmp_seq=%d time=%v\n",
-			pkt.Nbytes, pkt.IPAddr, pkt.Seq, pkt.Rtt)
+	if cfg.OnStart != nil {
+		pinger.OnSetup = func() {
+			cfg.OnStart(pinger.Addr(), pinger.IPAddr().String())
+		}
 	}
 
-	pinger.OnDuplicateRecv = func(pkt *probing.Packet) {
-		fmt.Fprintf(out, "\t%d bytes from %s: icmp_seq=%d time=%v ttl=%v (DUP!)\n",
-			pkt.Nbytes, pkt.IPAddr, pkt.Seq, pkt.Rtt, pkt.TTL)
+	if cfg.OnReply != nil {
+		pinger.OnRecv = func(pkt *probing.Packet) {
+			cfg.OnReply(newReply(pkt, false))
+		}
+		pinger.OnDuplicateRecv = func(pkt *probing.Packet) {
+			cfg.OnReply(newReply(pkt, true))
+		}
 	}
 
-	pinger.OnFinish = func(stats *probing.Statistics) {
-		fmt.Fprintf(out, "\n\t--- %s ping statistics ---\n", stats.Addr)
-		fmt.Fprintf(out, "\t%d packets transmitted, %d packets received, %v%% packet loss\n",
-			stats.PacketsSent, stats.PacketsRecv, stats.PacketLoss)
-		fmt.Fprintf(out, "\tround-trip min/avg/max/stddev = %v/%v/%v/%v\n\n",
-			stats.MinRtt, stats.AvgRtt, stats.MaxRtt, stats.StdDevRtt)
+	err = pinger.RunWithContext(ctx)
+	if err != nil && ctx.Err() == nil {
+		return nil, fmt.Errorf("failed to ping target host: %w", err)
 	}
 
-	fmt.Fprintf(out, "PING %s (%s):\n", pinger.Addr(), pinger.IPAddr())
-	err = pinger.Run()
-	if err != nil {
-		return fmt.Errorf("failed to ping target host: %w", err)
-	}
-	return nil
+	res := newResult(host, pinger.Statistics())
+	res.StartTime = start
+	res.EndTime = time.Now()
+	return res, ctx.Err()
 }
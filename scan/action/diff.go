@@ -0,0 +1,157 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package action
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/soner3/net-scan/history"
+	"github.com/soner3/net-scan/scan"
+	"github.com/soner3/net-scan/util"
+)
+
+// ErrChanged is returned if two scans differ, so scripts can gate on the
+// exit code
+var ErrChanged = errors.New("changes detected")
+
+var diffOutputs = []string{util.OUTPUT_TEXT, util.OUTPUT_JSON, util.OUTPUT_JSONL, util.OUTPUT_CSV, util.OUTPUT_TSV}
+
+var diffHeader = []string{"kind", "host", "addr", "port", "protocol", "old", "new"}
+
+// RunResults decodes the results of a saved scan run
+func RunResults(run *history.Run) ([]scan.ScanResult, error) {
+	if run.Command != "scan" {
+		return nil, fmt.Errorf("%w: run %s is a %s run, not a scan", ErrValue, run.ID, run.Command)
+	}
+	results := []scan.ScanResult{}
+	if err := json.Unmarshal(run.Results, &results); err != nil {
+		return nil, fmt.Errorf("run %s: %w", run.ID, err)
+	}
+	return results, nil
+}
+
+// Describe a missing host or port state
+func stateOrNone(state string) string {
+	if state == "" {
+		return "not scanned"
+	}
+	return state
+}
+
+// Print the changes grouped by host in the human readable format
+func writeDiffText(out io.Writer, changes []scan.Change) error {
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(out, "No changes")
+		return err
+	}
+
+	output := ""
+	for i, c := range changes {
+		if i == 0 || changes[i-1].Host != c.Host {
+			if i > 0 {
+				output += "\n"
+			}
+			output += fmt.Sprintf("%s:\n", c.Host)
+		}
+		port := fmt.Sprintf("%d/%s", c.Port, c.Protocol)
+		if c.Addr != "" {
+			port = c.Addr + " " + port
+		}
+		switch c.Kind {
+		case scan.CHANGE_HOST_APPEARED:
+			output += fmt.Sprintf("\t+ host appeared (%s -> %s)\n", stateOrNone(c.Old), c.New)
+		case scan.CHANGE_HOST_VANISHED:
+			output += fmt.Sprintf("\t- host vanished (%s -> %s)\n", c.Old, stateOrNone(c.New))
+		case scan.CHANGE_PORT_OPENED:
+			output += fmt.Sprintf("\t+ %s opened (%s -> %s)\n", port, stateOrNone(c.Old), c.New)
+		case scan.CHANGE_PORT_CLOSED:
+			output += fmt.Sprintf("\t- %s closed (%s -> %s)\n", port, c.Old, stateOrNone(c.New))
+		case scan.CHANGE_SERVICE:
+			output += fmt.Sprintf("\t~ %s service: %s -> %s\n", port, c.Old, c.New)
+		case scan.CHANGE_BANNER:
+			output += fmt.Sprintf("\t~ %s banner: %q -> %q\n", port, c.Old, c.New)
+		}
+	}
+	_, err := fmt.Fprint(out, output)
+	return err
+}
+
+// One row per change, host changes have no port
+func diffRows(changes []scan.Change) [][]string {
+	rows := make([][]string, len(changes))
+	for i, c := range changes {
+		port := ""
+		if c.Port != 0 {
+			port = strconv.Itoa(c.Port)
+		}
+		rows[i] = []string{c.Kind, c.Host, c.Addr, port, c.Protocol, c.Old, c.New}
+	}
+	return rows
+}
+
+// WriteDiff writes the changes from the old to the new results and
+// returns ErrChanged if there are any
+func WriteDiff(out io.Writer, old, new []scan.ScanResult, output string) error {
+	if err := util.ValidateOutput(output, diffOutputs...); err != nil {
+		return err
+	}
+
+	changes := scan.Diff(old, new)
+	var err error
+	switch output {
+	case util.OUTPUT_JSON, util.OUTPUT_JSONL:
+		err = util.WriteJSON(out, output, changes)
+	case util.OUTPUT_CSV, util.OUTPUT_TSV:
+		err = util.WriteTable(out, output, diffHeader, diffRows(changes))
+	default:
+		err = writeDiffText(out, changes)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(changes) > 0 {
+		return fmt.Errorf("%w: %d changes", ErrChanged, len(changes))
+	}
+	return nil
+}
+
+// Compare two saved scan runs, given by their IDs or unambiguous prefixes
+func DiffAction(out io.Writer, store *history.Store, oldID, newID string, output string) error {
+	results := [][]scan.ScanResult{}
+	for _, id := range []string{oldID, newID} {
+		run, err := store.Load(id)
+		if err != nil {
+			return err
+		}
+		res, err := RunResults(run)
+		if err != nil {
+			return err
+		}
+		results = append(results, res)
+	}
+	return WriteDiff(out, results[0], results[1], output)
+}
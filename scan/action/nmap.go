@@ -0,0 +1,315 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package action
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/soner3/net-scan/scan"
+	"github.com/soner3/net-scan/util"
+)
+
+const nmapTimeFormat = "Mon Jan 2 15:04:05 2006"
+
+type nmapRun struct {
+	XMLName          xml.Name       `xml:"nmaprun"`
+	Scanner          string         `xml:"scanner,attr"`
+	Args             string         `xml:"args,attr"`
+	Start            int64          `xml:"start,attr"`
+	StartStr         string         `xml:"startstr,attr"`
+	Version          string         `xml:"version,attr"`
+	XMLOutputVersion string         `xml:"xmloutputversion,attr"`
+	ScanInfo         []nmapScanInfo `xml:"scaninfo"`
+	Hosts            []nmapHost     `xml:"host"`
+	RunStats         nmapRunStats   `xml:"runstats"`
+}
+
+type nmapScanInfo struct {
+	Type        string `xml:"type,attr"`
+	Protocol    string `xml:"protocol,attr"`
+	NumServices int    `xml:"numservices,attr"`
+	Services    string `xml:"services,attr"`
+}
+
+type nmapHost struct {
+	StartTime int64          `xml:"starttime,attr"`
+	EndTime   int64          `xml:"endtime,attr"`
+	Status    nmapStatus     `xml:"status"`
+	Address   *nmapAddress   `xml:"address"`
+	Hostnames []nmapHostname `xml:"hostnames>hostname"`
+	Ports     *nmapPorts     `xml:"ports"`
+	Times     *nmapTimes     `xml:"times"`
+}
+
+// Round trip times in microseconds
+type nmapTimes struct {
+	SRTT    int64 `xml:"srtt,attr"`
+	RTTVar  int64 `xml:"rttvar,attr"`
+	Timeout int64 `xml:"to,attr"`
+}
+
+type nmapStatus struct {
+	State  string `xml:"state,attr"`
+	Reason string `xml:"reason,attr"`
+}
+
+type nmapAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+}
+
+type nmapHostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type nmapPorts struct {
+	Ports []nmapPort `xml:"port"`
+}
+
+type nmapPort struct {
+	Protocol string       `xml:"protocol,attr"`
+	PortID   int          `xml:"portid,attr"`
+	State    nmapState    `xml:"state"`
+	Service  *nmapService `xml:"service"`
+}
+
+type nmapState struct {
+	State  string `xml:"state,attr"`
+	Reason string `xml:"reason,attr"`
+}
+
+type nmapService struct {
+	Name      string `xml:"name,attr"`
+	Product   string `xml:"product,attr,omitempty"`
+	Version   string `xml:"version,attr,omitempty"`
+	ExtraInfo string `xml:"extrainfo,attr,omitempty"`
+	Method    string `xml:"method,attr"`
+	Conf      int    `xml:"conf,attr"`
+}
+
+type nmapRunStats struct {
+	Finished nmapFinished `xml:"finished"`
+	Hosts    nmapHosts    `xml:"hosts"`
+}
+
+type nmapFinished struct {
+	Time     int64  `xml:"time,attr"`
+	TimeStr  string `xml:"timestr,attr"`
+	Elapsed  string `xml:"elapsed,attr"`
+	Exit     string `xml:"exit,attr"`
+	ErrorMsg string `xml:"errormsg,attr,omitempty"`
+}
+
+type nmapHosts struct {
+	Up    int `xml:"up,attr"`
+	Down  int `xml:"down,attr"`
+	Total int `xml:"total,attr"`
+}
+
+// Protocol name nmap uses for a network (tcp4 -> tcp)
+func nmapProtocol(network string) string {
+	return strings.TrimRight(network, "46")
+}
+
+// Map a port state to the nmap vocabulary. nmap reports unreachable
+// hosts as filtered. Failed probes have no nmap state, their ports were
+// not scanned and are left out.
+func nmapPortState(ps *scan.PortState) (string, bool) {
+	switch ps.Open {
+	case scan.UNREACHABLE:
+		return "filtered", true
+	case scan.ERROR:
+		return "", false
+	}
+	return ps.Open.String(), true
+}
+
+func newNmapAddress(addr string) *nmapAddress {
+	addrType := "ipv4"
+	if ip := net.ParseIP(addr); ip != nil && ip.To4() == nil {
+		addrType = "ipv6"
+	}
+	return &nmapAddress{Addr: addr, AddrType: addrType}
+}
+
+// Convert the result of one scanned address of a host, addr is nil for
+// hosts which were not found or are down
+func newNmapHost(res *scan.ScanResult, addr *scan.AddressResult) nmapHost {
+	h := nmapHost{
+		StartTime: res.StartTime.Unix(),
+		EndTime:   res.EndTime.Unix(),
+		Hostnames: []nmapHostname{{Name: res.Host, Type: "user"}},
+	}
+	if res.NotFound {
+		h.Status = nmapStatus{State: "down", Reason: "no-resolve"}
+		return h
+	}
+
+	if res.Down {
+		h.Status = nmapStatus{State: "down", Reason: res.Reason}
+		h.Address = newNmapAddress(res.Addr)
+		return h
+	}
+
+	// Without discovery the host is assumed to be up like nmap -Pn does
+	h.Status = nmapStatus{State: "up", Reason: "user-set"}
+	if res.Reason != "" {
+		h.Status.Reason = res.Reason
+	}
+	h.Address = newNmapAddress(addr.Addr)
+
+	h.Ports = &nmapPorts{}
+	for _, ps := range *addr.PortStates {
+		state, ok := nmapPortState(&ps)
+		if !ok {
+			continue
+		}
+		port := nmapPort{
+			Protocol: nmapProtocol(ps.Protocol),
+			PortID:   ps.Port,
+			State:    nmapState{State: state, Reason: ps.Reason},
+		}
+		if ps.Service != nil {
+			port.Service = &nmapService{
+				Name:      ps.Service.Name,
+				Product:   ps.Service.Product,
+				Version:   ps.Service.Version,
+				ExtraInfo: ps.Service.Info,
+				Method:    "probed",
+				Conf:      10,
+			}
+		}
+		h.Ports.Ports = append(h.Ports.Ports, port)
+	}
+	if t := addr.Timing; t != nil {
+		h.Times = &nmapTimes{SRTT: t.SRTT.Microseconds(), RTTVar: t.RTTVar.Microseconds(), Timeout: t.Timeout.Microseconds()}
+	}
+	return h
+}
+
+// Describe the scanned ports of one protocol, nmap lists one scaninfo
+// element per protocol. TCP ports were either connected or sent a SYN.
+func newNmapScanInfo(protocol string, ports []int, syn bool) nmapScanInfo {
+	services := make([]string, len(ports))
+	for i, p := range ports {
+		services[i] = strconv.Itoa(p)
+	}
+
+	scanType := "connect"
+	switch {
+	case protocol == scan.PROTO_UDP:
+		scanType = "udp"
+	case syn:
+		scanType = "syn"
+	}
+	return nmapScanInfo{
+		Type:        scanType,
+		Protocol:    protocol,
+		NumServices: len(ports),
+		Services:    strings.Join(services, ","),
+	}
+}
+
+// Write the results as nmap compatible XML. The udp ports are the ones
+// probed in addition to the ports of a tcp network, scanErr is the error
+// which ended the scan early, e.g. an interruption.
+func writeNmapXML(out io.Writer, results []scan.ScanResult, ports []int, udpPorts []int, opts *scan.Options, scanErr error) error {
+	start, end := time.Now(), time.Time{}
+	for _, res := range results {
+		if res.StartTime.Before(start) {
+			start = res.StartTime
+		}
+		if res.EndTime.After(end) {
+			end = res.EndTime
+		}
+	}
+	if end.IsZero() {
+		end = start
+	}
+
+	scanInfo := []nmapScanInfo{newNmapScanInfo(nmapProtocol(opts.Network), ports, opts.Syn)}
+	if len(udpPorts) > 0 {
+		scanInfo = append(scanInfo, newNmapScanInfo(scan.PROTO_UDP, udpPorts, false))
+	}
+
+	run := nmapRun{
+		Scanner:          "net-scan",
+		Args:             strings.Join(util.Redact(os.Args).([]string), " "),
+		Start:            start.Unix(),
+		StartStr:         start.Format(nmapTimeFormat),
+		Version:          util.VERSION,
+		XMLOutputVersion: "1.05",
+		ScanInfo:         scanInfo,
+		RunStats: nmapRunStats{
+			Finished: nmapFinished{
+				Time:    end.Unix(),
+				TimeStr: end.Format(nmapTimeFormat),
+				Elapsed: fmt.Sprintf("%.2f", end.Sub(start).Seconds()),
+				Exit:    "success",
+			},
+		},
+	}
+	if scanErr != nil {
+		run.RunStats.Finished.Exit = "error"
+		run.RunStats.Finished.ErrorMsg = scanErr.Error()
+	}
+
+	for _, res := range results {
+		// nmap reports every scanned address as a host of its own
+		hosts := []nmapHost{}
+		if res.NotFound || res.Down {
+			hosts = append(hosts, newNmapHost(&res, nil))
+		} else {
+			for _, addr := range res.AddressResults() {
+				hosts = append(hosts, newNmapHost(&res, &addr))
+			}
+		}
+		for _, h := range hosts {
+			if h.Status.State == "up" {
+				run.RunStats.Hosts.Up++
+			} else {
+				run.RunStats.Hosts.Down++
+			}
+			run.Hosts = append(run.Hosts, h)
+		}
+	}
+	run.RunStats.Hosts.Total = len(run.Hosts)
+
+	if _, err := fmt.Fprint(out, xml.Header+"<!DOCTYPE nmaprun>\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(run); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(out)
+	return err
+}
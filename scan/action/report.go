@@ -0,0 +1,121 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package action
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/soner3/net-scan/scan"
+	"github.com/soner3/net-scan/util"
+)
+
+// Open ports are the findings of a scan
+var openPortRule = util.SarifRule{
+	ID:                   "open-port",
+	Name:                 "OpenPort",
+	ShortDescription:     util.SarifMessage{Text: "A port accepts connections or answers probes"},
+	DefaultConfiguration: util.SarifConfiguration{Level: util.SARIF_WARNING},
+}
+
+// One test suite per host and one test case per port, named after the port
+// and the address if all addresses were scanned. Hosts which were not
+// found or are down fail a single case, ports fail if they are unreachable
+// or a local error prevented the probe.
+func writeJUnit(out io.Writer, results []scan.ScanResult) error {
+	report := &util.JUnitReport{Name: "net-scan scan"}
+	for _, res := range results {
+		suite := util.JUnitSuite{
+			Name:      res.Host,
+			Timestamp: res.StartTime.Format(time.RFC3339),
+			Time:      res.EndTime.Sub(res.StartTime).Seconds(),
+			Cases:     []util.JUnitCase{},
+		}
+		if res.NotFound || res.Down {
+			tc := util.JUnitCase{Name: "host", ClassName: res.Host}
+			if res.NotFound {
+				tc.Failure = &util.JUnitFailure{Message: "host not found", Type: "not-found"}
+			} else {
+				tc.Failure = &util.JUnitFailure{Message: fmt.Sprintf("host is down (%s)", res.Reason), Type: "down"}
+			}
+			suite.Cases = append(suite.Cases, tc)
+			report.Suites = append(report.Suites, suite)
+			continue
+		}
+
+		for _, addr := range res.AddressResults() {
+			for _, ps := range *addr.PortStates {
+				tc := util.JUnitCase{Name: fmt.Sprintf("%d/%s", ps.Port, ps.Protocol), ClassName: res.Host}
+				if res.Addresses != nil {
+					tc.Name = addr.Addr + " " + tc.Name
+				}
+				if ps.Open == scan.UNREACHABLE || ps.Open == scan.ERROR {
+					tc.Failure = &util.JUnitFailure{
+						Message: fmt.Sprintf("%s is %s (%s)", tc.Name, &ps.Open, ps.Reason),
+						Type:    ps.Open.String(),
+					}
+				}
+				suite.Cases = append(suite.Cases, tc)
+			}
+		}
+		report.Suites = append(report.Suites, suite)
+	}
+	return util.WriteJUnit(out, report)
+}
+
+// One finding per open port, located at the host and port or at the
+// address and port if all addresses were scanned
+func writeSarif(out io.Writer, results []scan.ScanResult) error {
+	log := util.NewSarifLog(openPortRule)
+	for _, res := range results {
+		if res.NotFound || res.Down {
+			continue
+		}
+		for _, addr := range res.AddressResults() {
+			target := res.Host
+			if res.Addresses != nil {
+				target = addr.Addr
+			}
+			for _, ps := range *addr.PortStates {
+				if ps.Open != scan.OPEN {
+					continue
+				}
+
+				port := strconv.Itoa(ps.Port)
+				message := fmt.Sprintf("Port %s/%s is open on %s", port, ps.Protocol, target)
+				properties := map[string]any{"host": res.Host, "addr": addr.Addr, "port": ps.Port, "protocol": ps.Protocol}
+				if ps.Service != nil {
+					message += fmt.Sprintf(" (%s)", ps.Service)
+					properties["service"] = ps.Service
+				}
+				if ps.Banner != "" {
+					properties["banner"] = ps.Banner
+				}
+				log.Add(openPortRule, net.JoinHostPort(target, port)+"/"+ps.Protocol, message, properties)
+			}
+		}
+	}
+	return util.WriteSarif(out, log)
+}
@@ -22,15 +22,19 @@ THE SOFTWARE.
 package action
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"slices"
-	"time"
+	"strconv"
+	"strings"
 
 	"github.com/soner3/net-scan/host"
 	"github.com/soner3/net-scan/scan"
+	"github.com/soner3/net-scan/scan/service"
 	"github.com/soner3/net-scan/util"
 )
 
@@ -40,35 +44,39 @@ var (
 	ErrFormat = errors.New("invalid value format")
 )
 
-var networks = []string{"tcp", "tcp4", "tcp6", "udp", "udp4", "udp6", "ip", "ip4", "ip6", "unix", "unixgram", "unixpacket"}
+var outputs = []string{util.OUTPUT_TEXT, util.OUTPUT_JSON, util.OUTPUT_JSONL, util.OUTPUT_CSV, util.OUTPUT_TSV, util.OUTPUT_NMAP_XML, util.OUTPUT_JUNIT, util.OUTPUT_SARIF}
 
-var cmp = func(a, b int) int {
-	if a < b {
-		return -1
-	} else if a > b {
-		return 1
-	} else {
-		return 0
-	}
-}
+var networks = []string{"tcp", "tcp4", "tcp6", "udp", "udp4", "udp6", "ip", "ip4", "ip6", "unix", "unixgram", "unixpacket"}
 
 type Config struct {
 	filename  string
-	ports     []int
+	ports     string
 	portRange string
-	network   string
-	timeout   time.Duration
+	udpPorts  []int
 	filter    string
+	output    string
+	opts      *scan.Options
+
+	// TopPorts adds the most common ports and Presets the ports of the
+	// named presets to the port expression
+	TopPorts int
+	Presets  []string
+
+	// Progress reports finished probes while scanning, nil disables it
+	Progress *util.Progress
+	// OnResult is called with the unfiltered result of every finished host,
+	// e.g. to record the run
+	OnResult func(res *scan.ScanResult)
 }
 
-func NewConfig(filename string, ports []int, portRange string, network string, timeout time.Duration, filter string) *Config {
+func NewConfig(filename string, ports string, portRange string, filter string, output string, opts *scan.Options) *Config {
 	return &Config{
 		filename:  filename,
 		ports:     ports,
 		portRange: portRange,
-		network:   network,
-		timeout:   timeout,
 		filter:    filter,
+		output:    output,
+		opts:      opts,
 	}
 }
 func (cfg *Config) validate() (*[]int, error) {
@@ -90,75 +98,203 @@ func (cfg *Config) validate() (*[]int, error) {
 		return nil, fmt.Errorf("%w: host file is empty", ErrEmpty)
 	}
 
-	if len(cfg.ports) == 0 && cfg.portRange == "" {
-		return nil, fmt.Errorf("%w: either --ports or --port-range must be set", ErrEmpty)
+	if cfg.ports == "" && cfg.portRange == "" && cfg.TopPorts == 0 && len(cfg.Presets) == 0 {
+		return nil, fmt.Errorf("%w: either --ports, --port-range, --top-ports or --preset must be set", ErrEmpty)
+	}
+
+	if !slices.Contains(networks, cfg.opts.Network) {
+		return nil, fmt.Errorf("%w: unsupported network '%s'", ErrValue, cfg.opts.Network)
 	}
 
-	rangePorts := util.Set[int]{}
-	for _, p := range cfg.ports {
-		if p < 1 || p > 65535 {
-			return nil, fmt.Errorf("%w: port %d is out of valid range (1–65535)", ErrValue, p)
+	// --port-range is kept for compatibility and takes the same expressions
+	protocol := scan.Protocol(cfg.opts.Network)
+	spec, err := scan.ParsePorts(cfg.ports+","+cfg.portRange, protocol)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.TopPorts < 0 {
+		return nil, fmt.Errorf("%w: top-ports must not be negative", ErrValue)
+	}
+	topPorts, err := scan.TopPorts(cfg.TopPorts, protocol)
+	if err != nil {
+		return nil, err
+	}
+	spec.Add(protocol, topPorts...)
+	for _, name := range cfg.Presets {
+		presetPorts, err := scan.Preset(name, protocol)
+		if err != nil {
+			return nil, err
 		}
-		rangePorts.Add(p)
+		spec.Add(protocol, presetPorts...)
+	}
+	if len(spec.TCP) == 0 && len(spec.UDP) == 0 {
+		return nil, fmt.Errorf("%w: the port selection holds no ports", ErrEmpty)
+	}
+
+	ports := spec.Ports(protocol)
+	switch {
+	case protocol == scan.PROTO_UDP && len(spec.TCP) > 0:
+		return nil, fmt.Errorf("%w: tcp ports (T:) can't be scanned on network '%s'", ErrValue, cfg.opts.Network)
+	case len(spec.UDP) > 0 && protocol == scan.PROTO_TCP && !strings.HasPrefix(cfg.opts.Network, scan.PROTO_TCP):
+		return nil, fmt.Errorf("%w: udp ports (U:) can't be scanned on network '%s'", ErrValue, cfg.opts.Network)
+	case protocol == scan.PROTO_TCP:
+		cfg.udpPorts = spec.UDP
+	}
+
+	if cfg.opts.Syn && cfg.opts.Network != "tcp" && cfg.opts.Network != "tcp4" {
+		return nil, fmt.Errorf("%w: syn scans need the network tcp or tcp4, not '%s'", ErrValue, cfg.opts.Network)
+	}
+	if cfg.opts.Syn && cfg.opts.Proxy != nil {
+		return nil, fmt.Errorf("%w: syn scans can't go through a proxy", ErrValue)
+	}
+
+	if cfg.opts.Proxy != nil && (!strings.HasPrefix(cfg.opts.Network, scan.PROTO_TCP) || len(spec.UDP) > 0) {
+		return nil, fmt.Errorf("%w: only tcp ports can be scanned through a proxy", ErrValue)
+	}
+
+	if !cfg.opts.Source.Supports(cfg.opts.Network) {
+		return nil, fmt.Errorf("%w: source address %s can't be used on network '%s'", ErrValue, cfg.opts.Source.IP, cfg.opts.Network)
+	}
+
+	if cfg.opts.Timeout <= 0 {
+		return nil, fmt.Errorf("%w: timeout must be greater than 0", ErrValue)
 	}
 
-	if cfg.portRange != "" {
-		var start, end int
-		if _, err := fmt.Sscanf(cfg.portRange, "%d-%d", &start, &end); err != nil {
-			return nil, fmt.Errorf("%w: port-range format must be start-end", ErrFormat)
+	if cfg.opts.Retries < 0 {
+		return nil, fmt.Errorf("%w: retries must not be negative", ErrValue)
+	}
+
+	if d := cfg.opts.Discovery; d != nil {
+		if d.Timeout <= 0 {
+			return nil, fmt.Errorf("%w: discovery-timeout must be greater than 0", ErrValue)
 		}
-		if start < 1 || end < 1 || start >= end || end > 65535 {
-			return nil, fmt.Errorf("%w: invalid port range %s", ErrValue, cfg.portRange)
+		for _, method := range d.Methods {
+			if !slices.Contains(scan.DiscoveryMethods(), method) {
+				return nil, fmt.Errorf("%w: unknown discovery method '%s' (supported: %v)", ErrValue, method, scan.DiscoveryMethods())
+			}
 		}
-		for p := start; p <= end; p++ {
-			rangePorts.Add(p)
+		if slices.Contains(d.Methods, scan.DISCOVERY_TCP) {
+			if len(d.Ports) == 0 {
+				return nil, fmt.Errorf("%w: tcp discovery needs discovery-ports", ErrEmpty)
+			}
+			for _, p := range d.Ports {
+				if p < 1 || p > 65535 {
+					return nil, fmt.Errorf("%w: discovery port %d out of range", ErrValue, p)
+				}
+			}
 		}
 	}
 
-	if !slices.Contains(networks, cfg.network) {
-		return nil, fmt.Errorf("%w: unsupported network '%s'", ErrValue, cfg.network)
+	if cfg.opts.Concurrency < 1 {
+		return nil, fmt.Errorf("%w: concurrency must be greater than 0", ErrValue)
 	}
 
-	if cfg.timeout <= 0 {
-		return nil, fmt.Errorf("%w: timeout must be greater than 0", ErrValue)
+	if cfg.opts.HostConcurrency < 1 {
+		return nil, fmt.Errorf("%w: host-concurrency must be greater than 0", ErrValue)
 	}
 
-	validFilters := []string{"open", "closed", "timeout", ""}
-	if !slices.Contains(validFilters, cfg.filter) {
+	// "timeout" is kept as an alias for the filtered state
+	if cfg.filter == "timeout" {
+		cfg.filter = "filtered"
+	}
+	if cfg.filter != "" && !slices.Contains(scan.StateNames(), cfg.filter) {
 		return nil, fmt.Errorf("%w: unknown filter '%s'", ErrValue, cfg.filter)
 	}
 
-	return rangePorts.ToSortedSlice(cmp), nil
+	if err := util.ValidateOutput(cfg.output, outputs...); err != nil {
+		return nil, err
+	}
+
+	return &ports, nil
 }
 
-func ScanAction(out io.Writer, cfg *Config) error {
-	resolvedPorts, err := cfg.validate()
-	if err != nil {
-		return err
+// Format a single port state line. Unreachable hosts and local
+// errors carry their reason so they are not mistaken for a closed port,
+// retried probes the number of retries.
+// A detected service and a grabbed banner are printed on their own
+// lines below the port.
+func formatPortState(ps *scan.PortState) string {
+	line := fmt.Sprintf("\t%d/%s: %s", ps.Port, ps.Protocol, &ps.Open)
+	if ps.Open == scan.UNREACHABLE || ps.Open == scan.ERROR {
+		line += fmt.Sprintf(" (%s)", ps.Reason)
 	}
+	if ps.Retries > 0 {
+		line += fmt.Sprintf(" (retries: %d)", ps.Retries)
+	}
+	line += "\n"
+	if ps.Service != nil {
+		line += fmt.Sprintf("\t\tservice: %s\n", ps.Service)
+	}
+	if ps.Banner != "" {
+		line += fmt.Sprintf("\t\tbanner: %s\n", ps.Banner)
+	}
+	return line
+}
 
-	hl := host.NewHostList()
-	if err := hl.Load(cfg.filename); err != nil {
-		return err
+// Keep only the ports matching the state filter
+func filterResults(results []scan.ScanResult, filter string) []scan.ScanResult {
+	if filter == "" {
+		return results
+	}
+	filterStates := func(states *[]scan.PortState) *[]scan.PortState {
+		filtered := []scan.PortState{}
+		for _, ps := range *states {
+			if ps.Open.String() == filter {
+				filtered = append(filtered, ps)
+			}
+		}
+		return &filtered
+	}
+
+	filtered := make([]scan.ScanResult, len(results))
+	for i, res := range results {
+		res.PortStates = filterStates(res.PortStates)
+		if res.Addresses != nil {
+			addrs := make([]scan.AddressResult, len(res.Addresses))
+			for j, addr := range res.Addresses {
+				addr.PortStates = filterStates(addr.PortStates)
+				addrs[j] = addr
+			}
+			res.Addresses = addrs
+		}
+		filtered[i] = res
 	}
+	return filtered
+}
 
-	result := scan.Run(hl, resolvedPorts, cfg.network, cfg.timeout)
+// Indent every line by a tab
+func indent(text string) string {
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "\t" + line
+		}
+	}
+	return strings.Join(lines, "")
+}
 
-	for _, res := range *result {
+// Print the results in the human readable format
+func writeText(out io.Writer, results []scan.ScanResult) error {
+	for _, res := range results {
 		output := fmt.Sprintf("%s:\n", res.Host)
 		if res.NotFound {
 			output += "\tNot Found\n"
+		} else if res.Down {
+			output += fmt.Sprintf("\tDown (%s)\n", res.Reason)
 		} else {
-			portState := res.PortStates
-			for _, ps := range *portState {
-				if cfg.filter != "" {
-					if cfg.filter == ps.Open.String() {
-						output += fmt.Sprintf("\t%d/%s: %s\n", ps.Port, cfg.network, &ps.Open)
-					}
-				} else {
-					output += fmt.Sprintf("\t%d/%s: %s\n", ps.Port, cfg.network, &ps.Open)
+			// Every scanned address gets its own block below the host
+			for _, addr := range res.AddressResults() {
+				block := ""
+				if t := addr.Timing; t != nil {
+					block += fmt.Sprintf("\ttiming: srtt %s, rttvar %s, timeout %s (%d samples)\n", t.SRTT, t.RTTVar, t.Timeout, t.Samples)
 				}
-
+				for _, ps := range *addr.PortStates {
+					block += formatPortState(&ps)
+				}
+				if res.Addresses != nil {
+					block = fmt.Sprintf("\t%s:\n", addr.Addr) + indent(block)
+				}
+				output += block
 			}
 		}
 		output += "\n"
@@ -167,8 +303,153 @@ func ScanAction(out io.Writer, cfg *Config) error {
 		if err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
+var tableHeader = []string{"host", "not_found", "port", "protocol", "state", "reason", "service", "product", "version", "info", "banner", "addr"}
+
+// One row per host, address and port. Hosts which were not found get a
+// single row, down hosts a single row with the state "down".
+func tableRows(results []scan.ScanResult) [][]string {
+	rows := [][]string{}
+	for _, res := range results {
+		if res.NotFound {
+			rows = append(rows, []string{res.Host, "true", "", "", "", "", "", "", "", "", "", ""})
+			continue
+		}
+		if res.Down {
+			rows = append(rows, []string{res.Host, "false", "", "", "down", res.Reason, "", "", "", "", "", res.Addr})
+			continue
+		}
+		for _, addr := range res.AddressResults() {
+			for _, ps := range *addr.PortStates {
+				var svc service.Service
+				if ps.Service != nil {
+					svc = *ps.Service
+				}
+				rows = append(rows, []string{
+					res.Host, "false", strconv.Itoa(ps.Port), ps.Protocol, ps.Open.String(), ps.Reason,
+					svc.Name, svc.Product, svc.Version, svc.Info, ps.Banner, addr.Addr,
+				})
+			}
+		}
 	}
+	return rows
+}
 
-	return nil
+// Create the writer which handles every finished host. Text, jsonl, csv and
+// tsv are written as soon as a host is done, the remaining formats need all
+// results and are written by the returned flush function. It gets the error
+// which ended the scan early, if any.
+func newResultWriter(out io.Writer, cfg *Config, ports []int) (func(res scan.ScanResult) error, func(scanErr error) error, error) {
+	switch cfg.output {
+	case util.OUTPUT_JSONL:
+		enc := json.NewEncoder(out)
+		return func(res scan.ScanResult) error { return enc.Encode(res) }, func(error) error { return nil }, nil
+	case util.OUTPUT_CSV, util.OUTPUT_TSV:
+		w, err := util.NewTableWriter(out, cfg.output, tableHeader)
+		if err != nil {
+			return nil, nil, err
+		}
+		write := func(res scan.ScanResult) error {
+			if err := w.WriteAll(tableRows([]scan.ScanResult{res})); err != nil {
+				return err
+			}
+			return w.Error()
+		}
+		return write, func(error) error { return nil }, nil
+	case util.OUTPUT_JSON, util.OUTPUT_NMAP_XML, util.OUTPUT_JUNIT, util.OUTPUT_SARIF:
+		results := []scan.ScanResult{}
+		collect := func(res scan.ScanResult) error {
+			results = append(results, res)
+			return nil
+		}
+		flush := func(scanErr error) error {
+			switch cfg.output {
+			case util.OUTPUT_JSON:
+				return util.WriteJSON(out, cfg.output, results)
+			case util.OUTPUT_JUNIT:
+				return writeJUnit(out, results)
+			case util.OUTPUT_SARIF:
+				return writeSarif(out, results)
+			}
+			return writeNmapXML(out, results, ports, cfg.udpPorts, cfg.opts, scanErr)
+		}
+		return collect, flush, nil
+	default:
+		write := func(res scan.ScanResult) error { return writeText(out, []scan.ScanResult{res}) }
+		return write, func(error) error { return nil }, nil
+	}
+}
+
+func ScanAction(ctx context.Context, out io.Writer, cfg *Config) error {
+	resolvedPorts, err := cfg.validate()
+	if err != nil {
+		return err
+	}
+
+	hl := host.NewHostList()
+	if err := hl.Load(cfg.filename); err != nil {
+		return err
+	}
+
+	out = cfg.Progress.Writer(out)
+	write, flush, err := newResultWriter(out, cfg, *resolvedPorts)
+	if err != nil {
+		return err
+	}
+
+	// A failing writer stops the scan, there is no one left to read it
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var writeErr error
+	opts := *cfg.opts
+	opts.UDPPorts = cfg.udpPorts
+	// Sockets on the local machine need no discovery
+	if strings.HasPrefix(opts.Network, "unix") {
+		opts.Discovery = nil
+	}
+	opts.OnPortState = func(host string, ps *scan.PortState) {
+		if ps.Open == scan.OPEN {
+			cfg.Progress.Add(1, 1)
+		} else {
+			cfg.Progress.Add(1, 0)
+		}
+	}
+	perHost := len(*resolvedPorts) + len(cfg.udpPorts)
+	opts.OnResult = func(res *scan.ScanResult) {
+		// Hosts which were not found or are down count as done without
+		// probing, hosts with several addresses needed more probes than
+		// expected
+		if res.NotFound || res.Down {
+			cfg.Progress.Add(perHost, 0)
+		} else if n := len(res.AddressResults()); n > 1 {
+			cfg.Progress.AddTotal((n - 1) * perHost)
+		}
+		if cfg.OnResult != nil {
+			cfg.OnResult(res)
+		}
+		if writeErr != nil {
+			return
+		}
+		if writeErr = write(filterResults([]scan.ScanResult{*res}, cfg.filter)[0]); writeErr != nil {
+			cancel()
+		}
+	}
+
+	cfg.Progress.Start(hl.TargetCount()*perHost, "probes", "open")
+	err = scan.Stream(ctx, hl.Targets(), *resolvedPorts, &opts)
+	cfg.Progress.Stop()
+	if writeErr != nil {
+		return writeErr
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	// The results of an interrupted scan are written but incomplete
+	if flushErr := flush(err); flushErr != nil {
+		return flushErr
+	}
+	return ctx.Err()
 }
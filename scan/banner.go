@@ -0,0 +1,88 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package scan
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const bannerSize = 512
+
+var httpPorts = []int{80, 81, 591, 3000, 5000, 8000, 8008, 8080, 8081, 8888}
+
+// Nudge sent to services which wait for the client to talk first
+func bannerNudge(port int) []byte {
+	if slices.Contains(httpPorts, port) {
+		return []byte("HEAD / HTTP/1.0\r\n\r\n")
+	}
+	return []byte("\r\n")
+}
+
+// Read the first bytes the server sends on an open connection. If the server
+// stays silent, a nudge is sent and the answer to it is used instead.
+func grabBanner(con net.Conn, port int, timeout time.Duration) string {
+	buf := make([]byte, bannerSize)
+	if err := con.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return ""
+	}
+	n, err := con.Read(buf)
+	if n == 0 && os.IsTimeout(err) {
+		if err := con.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return ""
+		}
+		if _, err := con.Write(bannerNudge(port)); err != nil {
+			return ""
+		}
+		n, _ = con.Read(buf)
+	}
+	return sanitizeBanner(buf[:n])
+}
+
+// Turn raw bytes into a single printable line. Whitespace runs collapse into
+// one space and everything else that is not printable is hex escaped.
+func sanitizeBanner(raw []byte) string {
+	var sb strings.Builder
+	space := false
+	for _, r := range strings.ToValidUTF8(string(raw), "�") {
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		case space && sb.Len() > 0:
+			sb.WriteByte(' ')
+		}
+		space = false
+
+		if unicode.IsPrint(r) {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteString(fmt.Sprintf("\\x%02x", r))
+		}
+	}
+	return sb.String()
+}
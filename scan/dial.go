@@ -0,0 +1,89 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package scan
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/soner3/net-scan/util"
+)
+
+// Open a connection for a probe. The dial waits for the limiter of the
+// options, binds to its source, goes through its proxy, is bound to the
+// context and gives up after the timeout.
+func (opts *Options) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	return opts.dialTimeout(ctx, network, address, opts.Timeout)
+}
+
+// Open a connection like dial, but give up after the given timeout
+func (opts *Options) dialTimeout(ctx context.Context, network, address string, timeout time.Duration) (net.Conn, error) {
+	if err := opts.wait(ctx, address); err != nil {
+		return nil, err
+	}
+	return opts.connect(ctx, network, address, timeout)
+}
+
+// Open a connection like dialTimeout, but from an ephemeral source port.
+// Discovery and inspection connect to the ports of the probes, which a fixed
+// --source-port can't connect to again.
+func (opts *Options) dialEphemeral(ctx context.Context, network, address string, timeout time.Duration) (net.Conn, error) {
+	if err := opts.wait(ctx, address); err != nil {
+		return nil, err
+	}
+	return opts.connectFrom(ctx, opts.Source.Ephemeral(), network, address, timeout)
+}
+
+// Wait until the limiter of the options lets a probe to the host of the
+// address through. The probe hands its slot of the global semaphore back
+// while it waits, a host waiting out its delay must not hold back the
+// probes of other hosts.
+func (opts *Options) wait(ctx context.Context, address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	if opts.Limiter == nil || opts.sem == nil {
+		return opts.Limiter.Wait(ctx, host)
+	}
+	<-opts.sem
+	err = opts.Limiter.Wait(ctx, host)
+	// The caller releases the slot after the probe, it must hold it again
+	opts.sem <- struct{}{}
+	return err
+}
+
+// Open a connection without waiting for the limiter, so its duration is
+// the round trip of the connection alone
+func (opts *Options) connect(ctx context.Context, network, address string, timeout time.Duration) (net.Conn, error) {
+	return opts.connectFrom(ctx, opts.Source, network, address, timeout)
+}
+
+// Open a connection like connect, but bound to the given source
+func (opts *Options) connectFrom(ctx context.Context, src *util.Source, network, address string, timeout time.Duration) (net.Conn, error) {
+	d := src.Dialer(network, timeout)
+	if opts.Proxy != nil {
+		return opts.Proxy.DialContext(ctx, d, network, address)
+	}
+	return d.DialContext(ctx, network, address)
+}
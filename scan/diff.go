@@ -0,0 +1,172 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package scan
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Kinds of changes between two scans
+const (
+	CHANGE_HOST_APPEARED = "host-appeared"
+	CHANGE_HOST_VANISHED = "host-vanished"
+	CHANGE_PORT_OPENED   = "port-opened"
+	CHANGE_PORT_CLOSED   = "port-closed"
+	CHANGE_BANNER        = "banner-changed"
+	CHANGE_SERVICE       = "service-changed"
+)
+
+// Change is one difference between two scans. Old and New hold the host
+// or port states, banners or services before and after.
+type Change struct {
+	Kind     string `json:"kind"`
+	Host     string `json:"host"`
+	Addr     string `json:"addr,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+	Old      string `json:"old"`
+	New      string `json:"new"`
+}
+
+// A port of a host, addressed only if all addresses were scanned
+type portKey struct {
+	addr     string
+	protocol string
+	port     int
+}
+
+func hostState(res *ScanResult) string {
+	switch {
+	case res == nil:
+		return ""
+	case res.NotFound:
+		return "not-found"
+	case res.Down:
+		return "down"
+	default:
+		return "up"
+	}
+}
+
+func portStates(res *ScanResult) map[portKey]PortState {
+	ports := map[portKey]PortState{}
+	if hostState(res) != "up" {
+		return ports
+	}
+	for _, addr := range res.AddressResults() {
+		if addr.PortStates == nil {
+			continue
+		}
+		key := portKey{}
+		if res.Addresses != nil {
+			key.addr = addr.Addr
+		}
+		for _, ps := range *addr.PortStates {
+			key.protocol, key.port = ps.Protocol, ps.Port
+			ports[key] = ps
+		}
+	}
+	return ports
+}
+
+// Diff compares the results of two scans of the same hosts. Hosts count as
+// present if they were found and up, ports as open only in the open state.
+// Banners and services are only compared if both scans have one, so a scan
+// without --banner or --service-detect reports no changes of them. The
+// changes are ordered by host (as in the new scan, vanished hosts last)
+// and port.
+func Diff(old, new []ScanResult) []Change {
+	oldHosts := map[string]*ScanResult{}
+	for i := range old {
+		oldHosts[old[i].Host] = &old[i]
+	}
+	newHosts := map[string]*ScanResult{}
+	hosts := []string{}
+	for i := range new {
+		newHosts[new[i].Host] = &new[i]
+		hosts = append(hosts, new[i].Host)
+	}
+	for _, res := range old {
+		if _, ok := newHosts[res.Host]; !ok {
+			hosts = append(hosts, res.Host)
+		}
+	}
+
+	changes := []Change{}
+	for _, host := range hosts {
+		before, after := oldHosts[host], newHosts[host]
+		oldState, newState := hostState(before), hostState(after)
+		if oldState != "up" && newState == "up" {
+			changes = append(changes, Change{Kind: CHANGE_HOST_APPEARED, Host: host, Old: oldState, New: newState})
+		} else if oldState == "up" && newState != "up" {
+			changes = append(changes, Change{Kind: CHANGE_HOST_VANISHED, Host: host, Old: oldState, New: newState})
+		}
+
+		oldPorts, newPorts := portStates(before), portStates(after)
+		keys := []portKey{}
+		for key := range oldPorts {
+			keys = append(keys, key)
+		}
+		for key := range newPorts {
+			if _, ok := oldPorts[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		slices.SortFunc(keys, func(a, b portKey) int {
+			return cmp.Or(cmp.Compare(a.addr, b.addr), cmp.Compare(a.port, b.port), cmp.Compare(a.protocol, b.protocol))
+		})
+
+		for _, key := range keys {
+			oldPs, wasScanned := oldPorts[key]
+			newPs, isScanned := newPorts[key]
+			change := Change{Host: host, Addr: key.addr, Port: key.port, Protocol: key.protocol}
+			if wasScanned {
+				change.Old = oldPs.Open.String()
+			}
+			if isScanned {
+				change.New = newPs.Open.String()
+			}
+
+			wasOpen := wasScanned && oldPs.Open == OPEN
+			isOpen := isScanned && newPs.Open == OPEN
+			switch {
+			case !wasOpen && isOpen:
+				change.Kind = CHANGE_PORT_OPENED
+				changes = append(changes, change)
+			case wasOpen && !isOpen:
+				change.Kind = CHANGE_PORT_CLOSED
+				changes = append(changes, change)
+			case wasOpen && isOpen:
+				if oldPs.Service != nil && newPs.Service != nil && *oldPs.Service != *newPs.Service {
+					change.Kind, change.Old, change.New = CHANGE_SERVICE, oldPs.Service.String(), newPs.Service.String()
+					changes = append(changes, change)
+				}
+				if oldPs.Banner != "" && newPs.Banner != "" && oldPs.Banner != newPs.Banner {
+					change.Kind, change.Old, change.New = CHANGE_BANNER, oldPs.Banner, newPs.Banner
+					changes = append(changes, change)
+				}
+			}
+		}
+	}
+	return changes
+}
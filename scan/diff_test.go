@@ -0,0 +1,101 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package scan_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/soner3/net-scan/scan"
+	"github.com/soner3/net-scan/scan/service"
+)
+
+func TestDiff(t *testing.T) {
+	old := []scan.ScanResult{
+		{Host: "stable", PortStates: &[]scan.PortState{
+			{Port: 22, Protocol: "tcp", Open: scan.OPEN, Banner: "SSH-2.0-OpenSSH_8.9"},
+			{Port: 80, Protocol: "tcp", Open: scan.OPEN, Service: &service.Service{Name: "http", Product: "nginx", Version: "1.18"}},
+			{Port: 443, Protocol: "tcp", Open: scan.OPEN},
+			{Port: 8080, Protocol: "tcp", Open: scan.CLOSED},
+			{Port: 53, Protocol: "udp", Open: scan.OPEN_FILTERED},
+		}},
+		{Host: "vanished", PortStates: &[]scan.PortState{{Port: 22, Protocol: "tcp", Open: scan.OPEN}}},
+		{Host: "down", Down: true, PortStates: &[]scan.PortState{}},
+		{Host: "removed", PortStates: &[]scan.PortState{{Port: 80, Protocol: "tcp", Open: scan.CLOSED}}},
+	}
+	new := []scan.ScanResult{
+		{Host: "added", PortStates: &[]scan.PortState{{Port: 443, Protocol: "tcp", Open: scan.OPEN}}},
+		{Host: "stable", PortStates: &[]scan.PortState{
+			{Port: 22, Protocol: "tcp", Open: scan.OPEN, Banner: "SSH-2.0-OpenSSH_9.6"},
+			{Port: 80, Protocol: "tcp", Open: scan.OPEN, Service: &service.Service{Name: "http", Product: "nginx", Version: "1.24"}},
+			{Port: 443, Protocol: "tcp", Open: scan.FILTERED},
+			{Port: 8080, Protocol: "tcp", Open: scan.OPEN},
+			{Port: 53, Protocol: "udp", Open: scan.OPEN},
+		}},
+		{Host: "vanished", Down: true, PortStates: &[]scan.PortState{}},
+		{Host: "down", PortStates: &[]scan.PortState{{Port: 22, Protocol: "tcp", Open: scan.CLOSED}}},
+	}
+
+	expected := []scan.Change{
+		{Kind: scan.CHANGE_HOST_APPEARED, Host: "added", New: "up"},
+		{Kind: scan.CHANGE_PORT_OPENED, Host: "added", Port: 443, Protocol: "tcp", New: "open"},
+		{Kind: scan.CHANGE_BANNER, Host: "stable", Port: 22, Protocol: "tcp", Old: "SSH-2.0-OpenSSH_8.9", New: "SSH-2.0-OpenSSH_9.6"},
+		{Kind: scan.CHANGE_PORT_OPENED, Host: "stable", Port: 53, Protocol: "udp", Old: "open|filtered", New: "open"},
+		{Kind: scan.CHANGE_SERVICE, Host: "stable", Port: 80, Protocol: "tcp", Old: "http nginx 1.18", New: "http nginx 1.24"},
+		{Kind: scan.CHANGE_PORT_CLOSED, Host: "stable", Port: 443, Protocol: "tcp", Old: "open", New: "filtered"},
+		{Kind: scan.CHANGE_PORT_OPENED, Host: "stable", Port: 8080, Protocol: "tcp", Old: "closed", New: "open"},
+		{Kind: scan.CHANGE_HOST_VANISHED, Host: "vanished", Old: "up", New: "down"},
+		{Kind: scan.CHANGE_PORT_CLOSED, Host: "vanished", Port: 22, Protocol: "tcp", Old: "open"},
+		{Kind: scan.CHANGE_HOST_APPEARED, Host: "down", Old: "down", New: "up"},
+		{Kind: scan.CHANGE_HOST_VANISHED, Host: "removed", Old: "up"},
+	}
+
+	changes := scan.Diff(old, new)
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %+v, got %+v instead", expected, changes)
+	}
+
+	if changes := scan.Diff(new, new); len(changes) != 0 {
+		t.Errorf("Expected no changes, got %+v instead", changes)
+	}
+}
+
+func TestDiffAddresses(t *testing.T) {
+	// The states are decoded like the ones of a saved run
+	result := func(state string) scan.ScanResult {
+		var ports []scan.PortState
+		if err := json.Unmarshal([]byte(`[{"port":22,"protocol":"tcp","state":"`+state+`"}]`), &ports); err != nil {
+			t.Fatal(err)
+		}
+		return scan.ScanResult{Host: "host", Addresses: []scan.AddressResult{
+			{Addr: "10.0.0.1", PortStates: &ports},
+			{Addr: "::1", PortStates: &[]scan.PortState{}},
+		}}
+	}
+
+	changes := scan.Diff([]scan.ScanResult{result("filtered")}, []scan.ScanResult{result("open")})
+	expected := []scan.Change{{Kind: scan.CHANGE_PORT_OPENED, Host: "host", Addr: "10.0.0.1", Port: 22, Protocol: "tcp", Old: "filtered", New: "open"}}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %+v, got %+v instead", expected, changes)
+	}
+}
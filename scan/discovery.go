@@ -0,0 +1,267 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package scan
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	probing "github.com/prometheus-community/pro-bing"
+)
+
+// Methods of the host discovery
+const (
+	DISCOVERY_ICMP = "icmp"
+	DISCOVERY_TCP  = "tcp"
+	DISCOVERY_ARP  = "arp"
+)
+
+// Reasons why the discovery considered a host up
+const (
+	REASON_ECHO_REPLY   = "echo-reply"
+	REASON_ARP_RESPONSE = "arp-response"
+)
+
+// Ports probed by the TCP discovery
+var DISCOVERY_PORTS = []int{80, 443, 22, 3389}
+
+// Names of all discovery methods
+func DiscoveryMethods() []string {
+	return []string{DISCOVERY_ICMP, DISCOVERY_TCP, DISCOVERY_ARP}
+}
+
+// Discovery checks whether a host is up before its ports are scanned. A host
+// is up as soon as one method gets an answer from one of its addresses
+// (with a proxy only tcp is used):
+//   - icmp sends echo requests
+//   - tcp connects to Ports, an accepted and a refused connection both count
+//     (a SYN scan sends SYNs, a SYN/ACK and a RST both count)
+//   - arp looks for the address in the neighbor table of a local segment
+//     (IPv4 on Linux only)
+type Discovery struct {
+	Methods []string
+	Ports   []int
+	Timeout time.Duration
+	// Privileged sends ICMP echo requests over raw sockets instead of
+	// unprivileged datagram sockets
+	Privileged bool
+}
+
+func NewDiscovery(timeout time.Duration) *Discovery {
+	return &Discovery{
+		Methods: DiscoveryMethods(),
+		Ports:   DISCOVERY_PORTS,
+		Timeout: timeout,
+	}
+}
+
+// Probe all addresses with all methods at once and return whether the host
+// is up together with the reason. Every probe takes a slot of the semaphore.
+func (d *Discovery) discover(ctx context.Context, addrs []string, opts *Options) (bool, string) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	found := make(chan string, 1)
+	var wg sync.WaitGroup
+	run := func(probe func() (bool, string)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			opts.sem <- struct{}{}
+			up, reason := probe()
+			<-opts.sem
+			if up {
+				select {
+				case found <- reason:
+					cancel()
+				default:
+				}
+			}
+		}()
+	}
+
+	for _, addr := range addrs {
+		for _, method := range d.Methods {
+			// Only the tcp discovery goes through a proxy
+			if opts.Proxy != nil && method != DISCOVERY_TCP {
+				continue
+			}
+			switch method {
+			case DISCOVERY_ICMP:
+				run(func() (bool, string) { return d.echo(ctx, addr, opts) })
+			case DISCOVERY_TCP:
+				for _, port := range d.Ports {
+					run(func() (bool, string) { return d.connect(ctx, addr, port, opts) })
+				}
+			case DISCOVERY_ARP:
+				run(func() (bool, string) { return d.arp(ctx, addr, opts) })
+			}
+		}
+	}
+	wg.Wait()
+
+	select {
+	case reason := <-found:
+		return true, reason
+	default:
+		return false, REASON_NO_RESPONSE
+	}
+}
+
+// Send echo requests until the first reply. Probes which can't open their
+// socket, e.g. without permission, count as no answer.
+func (d *Discovery) echo(ctx context.Context, addr string, opts *Options) (bool, string) {
+	if err := opts.wait(ctx, addr); err != nil {
+		return false, ""
+	}
+	pinger, err := probing.NewPinger(addr)
+	if err != nil {
+		return false, ""
+	}
+	pinger.Count = 2
+	pinger.Interval = d.Timeout / 2
+	pinger.Timeout = d.Timeout
+	pinger.SetPrivileged(d.Privileged)
+	if src := opts.Source; src != nil {
+		if src.IP != nil {
+			pinger.Source = src.IP.String()
+		}
+		pinger.InterfaceName = src.Iface
+	}
+	pinger.OnRecv = func(*probing.Packet) {
+		pinger.Stop()
+	}
+	if err := pinger.RunWithContext(ctx); err != nil && ctx.Err() == nil {
+		return false, ""
+	}
+	return pinger.Statistics().PacketsRecv > 0, REASON_ECHO_REPLY
+}
+
+// Connect to the port, a host which accepts or refuses the connection is up.
+// A SYN scan sends a SYN instead, so the discovery completes no handshake
+// either.
+func (d *Discovery) connect(ctx context.Context, addr string, port int, opts *Options) (bool, string) {
+	if opts.syn != nil {
+		ps := opts.syn.scan(ctx, addr, port, PROTO_TCP, d.Timeout, opts)
+		return ps.Open == OPEN || ps.Open == CLOSED, ps.Reason
+	}
+	// The connection must not leave the fixed source port of the probes in
+	// TIME_WAIT towards a port which is scanned afterwards
+	con, err := opts.dialEphemeral(ctx, PROTO_TCP, net.JoinHostPort(addr, fmt.Sprintf("%d", port)), d.Timeout)
+	if err != nil {
+		s, reason := classify(err)
+		return s == CLOSED, reason
+	}
+	con.Close()
+	return true, REASON_CONNECTED
+}
+
+// Make the kernel resolve the hardware address of a local IPv4 address by
+// sending a datagram to the discard port and watch the neighbor table for
+// the answer. Addresses outside of the local segments are skipped.
+func (d *Discovery) arp(ctx context.Context, addr string, opts *Options) (bool, string) {
+	ip := net.ParseIP(addr).To4()
+	if ip == nil || !onLocalSegment(ip) {
+		return false, ""
+	}
+	if err := opts.Limiter.Wait(ctx, addr); err != nil {
+		return false, ""
+	}
+	con, err := opts.Source.Ephemeral().Dialer("udp4", d.Timeout).DialContext(ctx, "udp4", net.JoinHostPort(addr, "9"))
+	if err != nil {
+		return false, ""
+	}
+	defer con.Close()
+	con.Write([]byte{0})
+
+	deadline := time.NewTimer(d.Timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if neighbor(ip) {
+			return true, REASON_ARP_RESPONSE
+		}
+		select {
+		case <-ctx.Done():
+			return false, ""
+		case <-deadline.C:
+			return false, ""
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check if the address belongs to a network of a local interface other
+// than the loopback interface
+func onLocalSegment(ip net.IP) bool {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return false
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok && ipNet.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Check if the neighbor table holds a complete entry for the address. Only
+// Linux exposes the table in /proc, elsewhere nothing is found.
+func neighbor(ip net.IP) bool {
+	file, err := os.Open("/proc/net/arp")
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	// IP address, HW type, Flags, HW address, Mask, Device
+	scanner := bufio.NewScanner(file)
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[0] != ip.String() {
+			continue
+		}
+		// 0x2 marks a completed entry
+		flags, err := strconv.ParseUint(fields[2], 0, 32)
+		return err == nil && flags&0x2 != 0 && fields[3] != "00:00:00:00:00:00"
+	}
+	return false
+}
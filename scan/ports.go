@@ -0,0 +1,322 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package scan
+
+import (
+	"cmp"
+	_ "embed"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/soner3/net-scan/util"
+)
+
+const (
+	PROTO_TCP = "tcp"
+	PROTO_UDP = "udp"
+)
+
+var (
+	ErrPortSyntax = errors.New("invalid port expression")
+	ErrPortValue  = errors.New("invalid port")
+)
+
+//go:embed ports.db
+var portTableData string
+
+// An entry of the embedded port table
+type portEntry struct {
+	name     string
+	port     int
+	protocol string
+	presets  []string
+}
+
+// The embedded port table, most common ports first
+var portTable = mustParsePortTable(portTableData)
+
+// Parse the embedded port table. Errors are bugs of the table, so they
+// panic.
+func mustParsePortTable(data string) []portEntry {
+	entries := []portEntry{}
+	for i, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			panic(fmt.Sprintf("ports.db:%d: expected <name> <port>/<protocol> [<preset>,...]", i+1))
+		}
+		port, protocol, _ := strings.Cut(fields[1], "/")
+		p, err := strconv.Atoi(port)
+		if err != nil || (protocol != PROTO_TCP && protocol != PROTO_UDP) {
+			panic(fmt.Sprintf("ports.db:%d: invalid port '%s'", i+1, fields[1]))
+		}
+		entry := portEntry{name: fields[0], port: p, protocol: protocol}
+		if len(fields) == 3 {
+			entry.presets = strings.Split(fields[2], ",")
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Look up the port of a service name of the port table
+func servicePort(name string) (int, bool) {
+	for _, e := range portTable {
+		if e.name != "-" && strings.EqualFold(e.name, name) {
+			return e.port, true
+		}
+	}
+	return 0, false
+}
+
+// KnownPorts returns the number of ports of the protocol in the port table,
+// the most TopPorts can return
+func KnownPorts(protocol string) int {
+	n := 0
+	for _, e := range portTable {
+		if e.protocol == protocol {
+			n++
+		}
+	}
+	return n
+}
+
+// TopPorts returns the n most common ports of the protocol. It fails if n
+// exceeds the ports known to the port table.
+func TopPorts(n int, protocol string) ([]int, error) {
+	if known := KnownPorts(protocol); n > known {
+		return nil, fmt.Errorf("%w: only the top %d %s ports are known", ErrPortValue, known, protocol)
+	}
+	ports := []int{}
+	for _, e := range portTable {
+		if len(ports) == n {
+			break
+		}
+		if e.protocol == protocol {
+			ports = append(ports, e.port)
+		}
+	}
+	return ports, nil
+}
+
+// Presets returns the names of all presets in the port table
+func Presets() []string {
+	names := []string{}
+	for _, e := range portTable {
+		for _, preset := range e.presets {
+			if !slices.Contains(names, preset) {
+				names = append(names, preset)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Preset returns the ports of the protocol belonging to a preset
+func Preset(name string, protocol string) ([]int, error) {
+	if !slices.Contains(Presets(), name) {
+		return nil, fmt.Errorf("%w: unknown preset '%s' (supported: %v)", ErrPortValue, name, Presets())
+	}
+	ports := []int{}
+	for _, e := range portTable {
+		if e.protocol == protocol && slices.Contains(e.presets, name) {
+			ports = append(ports, e.port)
+		}
+	}
+	return ports, nil
+}
+
+// PortSpec holds the ports of a port expression per transport protocol,
+// sorted and without duplicates
+type PortSpec struct {
+	TCP []int
+	UDP []int
+
+	excluded map[string]map[int]bool
+}
+
+// Add ports of the protocol unless the expression excluded them
+func (spec *PortSpec) Add(protocol string, ports ...int) {
+	set := util.Set[int]{}
+	for _, p := range spec.Ports(protocol) {
+		set.Add(p)
+	}
+	for _, p := range ports {
+		if !spec.excluded[protocol][p] {
+			set.Add(p)
+		}
+	}
+
+	merged := *set.ToSortedSlice(cmp.Compare[int])
+	if protocol == PROTO_UDP {
+		spec.UDP = merged
+	} else {
+		spec.TCP = merged
+	}
+}
+
+// Ports returns the ports of the protocol, tcp or udp
+func (spec *PortSpec) Ports(protocol string) []int {
+	if protocol == PROTO_UDP {
+		return spec.UDP
+	}
+	return spec.TCP
+}
+
+// Protocol returns the transport protocol of a network, tcp or udp. Other
+// networks like ip or unix are treated as tcp.
+func Protocol(network string) string {
+	if strings.HasPrefix(network, PROTO_UDP) {
+		return PROTO_UDP
+	}
+	return PROTO_TCP
+}
+
+// ParsePorts parses a comma separated port expression. Every item is one of
+//
+//	80          a single port
+//	80-90       a range
+//	-1024       a range starting at port 1
+//	60000-      a range ending at port 65535
+//	ssh         a well known service name
+//
+// A "T:" or "U:" prefix selects tcp or udp for the item and all following
+// items until the next prefix, items without a prefix use defaultProtocol.
+// Items starting with "!" are excluded from the protocol in effect, or from
+// both protocols if no prefix was given yet. Exclusions apply to the whole
+// expression regardless of their position.
+func ParsePorts(expr string, defaultProtocol string) (*PortSpec, error) {
+	include := map[string]map[int]bool{PROTO_TCP: {}, PROTO_UDP: {}}
+	exclude := map[string]map[int]bool{PROTO_TCP: {}, PROTO_UDP: {}}
+
+	scope := ""
+	for item := range strings.SplitSeq(expr, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		excluded := strings.HasPrefix(item, "!")
+		item = strings.TrimPrefix(item, "!")
+
+		itemScope := scope
+		if prefix, rest, ok := strings.Cut(item, ":"); ok {
+			switch strings.ToUpper(prefix) {
+			case "T":
+				itemScope = PROTO_TCP
+			case "U":
+				itemScope = PROTO_UDP
+			default:
+				return nil, fmt.Errorf("%w: unknown protocol prefix '%s:'", ErrPortSyntax, prefix)
+			}
+			item = rest
+			// A prefix of an exclusion only applies to the exclusion
+			if !excluded {
+				scope = itemScope
+			}
+		}
+
+		start, end, err := parsePortItem(item)
+		if err != nil {
+			return nil, err
+		}
+
+		protocols := []string{defaultProtocol}
+		if itemScope != "" {
+			protocols = []string{itemScope}
+		} else if excluded {
+			protocols = []string{PROTO_TCP, PROTO_UDP}
+		}
+
+		target := include
+		if excluded {
+			target = exclude
+		}
+		for _, proto := range protocols {
+			for p := start; p <= end; p++ {
+				target[proto][p] = true
+			}
+		}
+	}
+
+	spec := &PortSpec{TCP: []int{}, UDP: []int{}, excluded: exclude}
+	for proto, ports := range include {
+		spec.Add(proto, slices.Collect(maps.Keys(ports))...)
+	}
+	return spec, nil
+}
+
+// Parse a single port, range or service name into an inclusive range
+func parsePortItem(item string) (int, int, error) {
+	if item == "" {
+		return 0, 0, fmt.Errorf("%w: empty item", ErrPortSyntax)
+	}
+
+	if p, ok := servicePort(item); ok {
+		return p, p, nil
+	}
+
+	first, last, isRange := strings.Cut(item, "-")
+	if !isRange {
+		p, err := parsePort(item)
+		return p, p, err
+	}
+
+	start, end := 1, 65535
+	var err error
+	if first != "" {
+		if start, err = parsePort(first); err != nil {
+			return 0, 0, err
+		}
+	}
+	if last != "" {
+		if end, err = parsePort(last); err != nil {
+			return 0, 0, err
+		}
+	}
+	if first == "" && last == "" {
+		return 0, 0, fmt.Errorf("%w: '-' needs a start or an end", ErrPortSyntax)
+	}
+	if start > end {
+		return 0, 0, fmt.Errorf("%w: range %s ends before it starts", ErrPortValue, item)
+	}
+	return start, end, nil
+}
+
+// Parse a port number and check its range
+func parsePort(s string) (int, error) {
+	p, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: '%s' is neither a port nor a known service", ErrPortSyntax, s)
+	}
+	if p < 1 || p > 65535 {
+		return 0, fmt.Errorf("%w: port %d is out of valid range (1-65535)", ErrPortValue, p)
+	}
+	return p, nil
+}
@@ -0,0 +1,169 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package scan_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/soner3/net-scan/scan"
+)
+
+func TestParsePorts(t *testing.T) {
+	testCases := []struct {
+		name        string
+		expr        string
+		protocol    string
+		expectedTCP []int
+		expectedUDP []int
+		expectedErr error
+	}{
+		{"List", "22,80,443", "tcp", []int{22, 80, 443}, []int{}, nil},
+		{"Ranges", "22,80-82,443,8000-8002", "tcp", []int{22, 80, 81, 82, 443, 8000, 8001, 8002}, []int{}, nil},
+		{"Duplicates", "80,79-81,80", "tcp", []int{79, 80, 81}, []int{}, nil},
+		{"DefaultUDP", "53,123", "udp", []int{}, []int{53, 123}, nil},
+		{"Prefixes", "T:80,U:53,161,T:443", "tcp", []int{80, 443}, []int{53, 161}, nil},
+		{"Services", "ssh,HTTPS,U:snmp", "tcp", []int{22, 443}, []int{161}, nil},
+		{"OpenStart", "-3", "tcp", []int{1, 2, 3}, []int{}, nil},
+		{"OpenEnd", "65533-", "tcp", []int{65533, 65534, 65535}, []int{}, nil},
+		{"Exclusions", "1-10,!2-8,U:1-3", "tcp", []int{1, 9, 10}, []int{1}, nil},
+		{"ExclusionBeforeRange", "!2,1-3", "tcp", []int{1, 3}, []int{}, nil},
+		{"ScopedExclusion", "T:1-3,U:1-3,!T:2", "tcp", []int{1, 3}, []int{1, 2, 3}, nil},
+		{"Spaces", " 22 , 80 ,", "tcp", []int{22, 80}, []int{}, nil},
+		{"ErrOutOfRange", "0", "tcp", nil, nil, scan.ErrPortValue},
+		{"ErrTooLarge", "1-65536", "tcp", nil, nil, scan.ErrPortValue},
+		{"ErrReversed", "90-80", "tcp", nil, nil, scan.ErrPortValue},
+		{"ErrUnknownService", "gopher-plus", "tcp", nil, nil, scan.ErrPortSyntax},
+		{"ErrPrefix", "X:80", "tcp", nil, nil, scan.ErrPortSyntax},
+		{"ErrDash", "-", "tcp", nil, nil, scan.ErrPortSyntax},
+		{"Empty", ",", "tcp", []int{}, []int{}, nil},
+		{"AllExcluded", "80,!80", "tcp", []int{}, []int{}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec, err := scan.ParsePorts(tc.expr, tc.protocol)
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Errorf("Expected %q, got %q instead", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %q instead", err)
+			}
+
+			if !slices.Equal(spec.TCP, tc.expectedTCP) {
+				t.Errorf("Expected tcp %v, got %v instead", tc.expectedTCP, spec.TCP)
+			}
+			if !slices.Equal(spec.UDP, tc.expectedUDP) {
+				t.Errorf("Expected udp %v, got %v instead", tc.expectedUDP, spec.UDP)
+			}
+		})
+	}
+}
+
+func TestPortSpecAdd(t *testing.T) {
+	spec, err := scan.ParsePorts("443,!23,!U:53", "tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Exclusions of the expression apply to added ports as well
+	top, err := scan.TopPorts(3, "tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec.Add("tcp", top...)
+	spec.Add("udp", 53, 161)
+
+	if expected := []int{80, 443}; !slices.Equal(spec.TCP, expected) {
+		t.Errorf("Expected tcp %v, got %v instead", expected, spec.TCP)
+	}
+	if expected := []int{161}; !slices.Equal(spec.UDP, expected) {
+		t.Errorf("Expected udp %v, got %v instead", expected, spec.UDP)
+	}
+}
+
+func TestTopPorts(t *testing.T) {
+	testCases := []struct {
+		name     string
+		n        int
+		protocol string
+		expected []int
+	}{
+		{"TCP", 5, "tcp", []int{80, 23, 443, 21, 22}},
+		{"UDP", 3, "udp", []int{631, 161, 137}},
+		{"None", 0, "tcp", []int{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := scan.TopPorts(tc.n, tc.protocol)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(out, tc.expected) {
+				t.Errorf("Expected %v, got %v instead", tc.expected, out)
+			}
+		})
+	}
+
+	if out, err := scan.TopPorts(100, "tcp"); err != nil || len(out) != 100 {
+		t.Errorf("Expected %d ports, got %d (%v) instead", 100, len(out), err)
+	}
+	// Asking for more ports than the table knows must not be capped silently
+	known := scan.KnownPorts("udp")
+	if out, err := scan.TopPorts(known, "udp"); err != nil || len(out) != known {
+		t.Errorf("Expected %d ports, got %d (%v) instead", known, len(out), err)
+	}
+	if _, err := scan.TopPorts(known+1, "udp"); !errors.Is(err, scan.ErrPortValue) {
+		t.Errorf("Expected %q, got %q instead", scan.ErrPortValue, err)
+	}
+}
+
+func TestPreset(t *testing.T) {
+	if expected := []string{"db", "web", "windows"}; !slices.Equal(scan.Presets(), expected) {
+		t.Errorf("Expected %v, got %v instead", expected, scan.Presets())
+	}
+
+	web, err := scan.Preset("web", "tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(web, 80) || !slices.Contains(web, 8443) || slices.Contains(web, 22) {
+		t.Errorf("Unexpected web ports %v", web)
+	}
+
+	windows, err := scan.Preset("windows", "udp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(windows, 137) || slices.Contains(windows, 445) {
+		t.Errorf("Unexpected windows udp ports %v", windows)
+	}
+
+	if _, err := scan.Preset("mail", "tcp"); !errors.Is(err, scan.ErrPortValue) {
+		t.Errorf("Expected %q, got %q instead", scan.ErrPortValue, err)
+	}
+}
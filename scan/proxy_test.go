@@ -0,0 +1,161 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package scan_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/soner3/net-scan/host"
+	"github.com/soner3/net-scan/scan"
+	"github.com/soner3/net-scan/util"
+)
+
+// Port for which the SOCKS5 stand-in answers host unreachable
+const unreachablePort = 1
+
+// Start a SOCKS5 server which connects to the requested IPv4 targets and
+// answers with the reply codes of RFC 1928. Every requested port is sent to
+// the returned channel.
+func socks5StandIn(t *testing.T) (string, chan int) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	requested := make(chan int, 10)
+	go func() {
+		for {
+			con, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer con.Close()
+				// Greeting without authentication
+				buf := make([]byte, 3)
+				if _, err := io.ReadFull(con, buf); err != nil {
+					return
+				}
+				con.Write([]byte{0x05, 0x00})
+
+				// CONNECT request with an IPv4 address
+				req := make([]byte, 10)
+				if _, err := io.ReadFull(con, req); err != nil {
+					return
+				}
+				port := int(binary.BigEndian.Uint16(req[8:]))
+				requested <- port
+
+				reply := byte(0x00)
+				var target net.Conn
+				if port == unreachablePort {
+					reply = 0x04
+				} else if target, err = net.Dial("tcp4", (&net.TCPAddr{IP: net.IP(req[4:8]), Port: port}).String()); err != nil {
+					reply = 0x01
+					if errors.Is(err, syscall.ECONNREFUSED) {
+						reply = 0x05
+					}
+				}
+				con.Write([]byte{0x05, reply, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+				if target != nil {
+					target.Close()
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), requested
+}
+
+func TestRunProxy(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	closed, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	addr, requested := socks5StandIn(t)
+	proxy, err := util.NewProxy("socks5://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hl := host.NewHostList()
+	hl.Add("127.0.0.1")
+	opts := scan.NewOptions("tcp4", time.Second, 10, 10)
+	opts.Proxy = proxy
+	ports := []int{ln.Addr().(*net.TCPAddr).Port, closedPort, unreachablePort}
+	res, _ := scan.Run(context.Background(), hl.Targets(), ports, opts)
+
+	expected := []struct {
+		state  string
+		reason string
+	}{
+		{"open", scan.REASON_CONNECTED},
+		{"closed", scan.REASON_REFUSED},
+		{"unreachable", scan.REASON_HOST_UNREACH},
+	}
+	for i, ps := range *res[0].PortStates {
+		if ps.Open.String() != expected[i].state || ps.Reason != expected[i].reason {
+			t.Errorf("Expected %s (%s) for port %d, got %s (%s) instead", expected[i].state, expected[i].reason, ps.Port, ps.Open.String(), ps.Reason)
+		}
+	}
+	if len(requested) != len(ports) {
+		t.Errorf("Expected %d requests through the proxy, got %d instead", len(ports), len(requested))
+	}
+}
+
+func TestRunProxyUnreachable(t *testing.T) {
+	down, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy, err := util.NewProxy("socks5://" + down.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	down.Close()
+
+	hl := host.NewHostList()
+	hl.Add("127.0.0.1")
+	opts := scan.NewOptions("tcp4", time.Second, 10, 10)
+	opts.Proxy = proxy
+	res, _ := scan.Run(context.Background(), hl.Targets(), []int{80}, opts)
+
+	// A refused connection to the proxy says nothing about the port
+	if ps := (*res[0].PortStates)[0]; ps.Open != scan.ERROR {
+		t.Errorf("Expected error, got %s (%s) instead", ps.Open.String(), ps.Reason)
+	}
+}
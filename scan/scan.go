@@ -22,41 +22,126 @@ THE SOFTWARE.
 package scan
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"iter"
 	"net"
 	"os"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
-	"github.com/soner3/net-scan/host"
+	"github.com/soner3/net-scan/scan/service"
+	"github.com/soner3/net-scan/util"
 )
 
 type state int
 
 const (
 	OPEN state = iota
-	TIMEOUT
+	FILTERED
 	CLOSED
+	UNREACHABLE
+	ERROR
+	OPEN_FILTERED
 )
 
 var stateName = map[state]string{
-	OPEN:    "open",
-	TIMEOUT: "timeout",
-	CLOSED:  "closed",
+	OPEN:          "open",
+	FILTERED:      "filtered",
+	CLOSED:        "closed",
+	UNREACHABLE:   "unreachable",
+	ERROR:         "error",
+	OPEN_FILTERED: "open|filtered",
 }
 
 func (s *state) String() string {
 	return stateName[*s]
 }
 
+func (s state) MarshalText() ([]byte, error) {
+	return []byte(stateName[s]), nil
+}
+
+func (s *state) UnmarshalText(text []byte) error {
+	for candidate, name := range stateName {
+		if name == string(text) {
+			*s = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown port state '%s'", text)
+}
+
+// Names of all known port states
+func StateNames() []string {
+	names := make([]string, 0, len(stateName))
+	for s := OPEN; s <= OPEN_FILTERED; s++ {
+		names = append(names, stateName[s])
+	}
+	return names
+}
+
+// Reasons why a port ended up in its state
+const (
+	REASON_CONNECTED      = "syn-ack"
+	REASON_REFUSED        = "conn-refused"
+	REASON_NO_RESPONSE    = "no-response"
+	REASON_HOST_UNREACH   = "host-unreach"
+	REASON_NET_UNREACH    = "net-unreach"
+	REASON_PERMISSION     = "permission-denied"
+	REASON_TOO_MANY_FILES = "too-many-open-files"
+	REASON_UDP_RESPONSE   = "udp-response"
+	REASON_PORT_UNREACH   = "port-unreach"
+)
+
 type PortState struct {
-	Port int
-	Open state
+	Port     int              `json:"port"`
+	Protocol string           `json:"protocol"`
+	Open     state            `json:"state"`
+	Reason   string           `json:"reason"`
+	Banner   string           `json:"banner,omitempty"`
+	Service  *service.Service `json:"service,omitempty"`
+	Retries  int              `json:"retries,omitempty"`
+
+	// Round trip time of a probe which got an answer
+	rtt time.Duration
 }
 
 type ScanResult struct {
-	Host       string
-	NotFound   bool
-	PortStates *[]PortState
+	Host     string `json:"host"`
+	Addr     string `json:"addr,omitempty"`
+	NotFound bool   `json:"not_found"`
+	// Down is set if the discovery got no answer, the ports are not scanned
+	// then. Reason tells why the discovery considered the host up or down.
+	Down       bool         `json:"down,omitempty"`
+	Reason     string       `json:"reason,omitempty"`
+	StartTime  time.Time    `json:"start_time"`
+	EndTime    time.Time    `json:"end_time"`
+	PortStates *[]PortState `json:"ports"`
+	Timing     *HostTiming  `json:"timing,omitempty"`
+	// Addresses holds the results per address if all addresses of the
+	// host were scanned, PortStates is empty then
+	Addresses []AddressResult `json:"addresses,omitempty"`
+}
+
+// AddressResult holds the ports of one resolved address of a host
+type AddressResult struct {
+	Addr       string       `json:"addr"`
+	PortStates *[]PortState `json:"ports"`
+	Timing     *HostTiming  `json:"timing,omitempty"`
+}
+
+// AddressResults returns the results per scanned address. A host scanned
+// by its name yields a single result with the address it resolved to first.
+func (res *ScanResult) AddressResults() []AddressResult {
+	if res.Addresses != nil {
+		return res.Addresses
+	}
+	return []AddressResult{{Addr: res.Addr, PortStates: res.PortStates, Timing: res.Timing}}
 }
 
 func NewScanResult(host string) *ScanResult {
@@ -65,49 +150,387 @@ func NewScanResult(host string) *ScanResult {
 	}
 }
 
-func NewPortState(port int) *PortState {
+func NewPortState(port int, protocol string) *PortState {
 	return &PortState{
-		Port: port,
-		Open: CLOSED,
+		Port:     port,
+		Protocol: protocol,
+		Open:     CLOSED,
 	}
 }
 
-// Scan the port on the given host
-func scan(host string, port int, network string, timeout time.Duration) *PortState {
-	ps := NewPortState(port)
-	address := net.JoinHostPort(host, fmt.Sprintf("%d", ps.Port))
-	con, err := net.DialTimeout(network, address, timeout)
+// Classify a dial error into a port state and the reason behind it.
+// Errors caused by the local machine are never reported as a closed port.
+func classify(err error) (state, string) {
+	switch {
+	case os.IsTimeout(err):
+		return FILTERED, REASON_NO_RESPONSE
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return CLOSED, REASON_REFUSED
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.EHOSTDOWN):
+		return UNREACHABLE, REASON_HOST_UNREACH
+	case errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.ENETDOWN):
+		return UNREACHABLE, REASON_NET_UNREACH
+	case errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM):
+		return ERROR, REASON_PERMISSION
+	case errors.Is(err, syscall.EMFILE), errors.Is(err, syscall.ENFILE):
+		return ERROR, REASON_TOO_MANY_FILES
+	default:
+		var opErr *net.OpError
+		if errors.As(err, &opErr) {
+			return ERROR, opErr.Err.Error()
+		}
+		return ERROR, err.Error()
+	}
+}
+
+// Scan the port on the given host, waiting up to timeout for an answer
+func scan(ctx context.Context, host string, port int, network string, timeout time.Duration, opts *Options) *PortState {
+	if Protocol(network) == PROTO_UDP {
+		return scanUDP(ctx, host, port, network, timeout, opts)
+	}
+
+	address := net.JoinHostPort(host, fmt.Sprintf("%d", port))
+	if opts.syn != nil {
+		ps := opts.syn.scan(ctx, host, port, network, timeout, opts)
+		// Banners and services need a full connection
+		if ps.Open == OPEN && (opts.Banner || opts.Services != nil) {
+			if con, err := opts.dialEphemeral(ctx, network, address, opts.Timeout); err == nil {
+				defer con.Close()
+				inspect(ctx, con, ps, host, network, opts)
+			}
+		}
+		return ps
+	}
+
+	ps := NewPortState(port, network)
+	// The round trip time starts once the limiter let the probe through
+	if err := opts.wait(ctx, address); err != nil {
+		ps.Open, ps.Reason = classify(err)
+		return ps
+	}
+	start := time.Now()
+	con, err := opts.connect(ctx, network, address, timeout)
 	if err != nil {
-		if os.IsTimeout(err) {
-			ps.Open = TIMEOUT
+		ps.Open, ps.Reason = classify(err)
+		if ps.Open == CLOSED {
+			ps.rtt = time.Since(start)
 		}
 		return ps
 	}
-	con.Close()
+	defer con.Close()
+	ps.rtt = time.Since(start)
 	ps.Open = OPEN
+	ps.Reason = REASON_CONNECTED
+	inspect(ctx, con, ps, host, network, opts)
 	return ps
 }
 
-// Run the scann process for all hosts
-func Run(hl *host.HostList, ports *[]int, network string, timeout time.Duration) *[]ScanResult {
-	results := make([]ScanResult, len(hl.Hosts))
+// Grab the banner and detect the service of an open port
+func inspect(ctx context.Context, con net.Conn, ps *PortState, host string, network string, opts *Options) {
+	if opts.Banner {
+		ps.Banner = grabBanner(con, ps.Port, opts.Timeout)
+	}
+	// The probe connection is still open, service probes connect again
+	if opts.Services != nil {
+		dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+			return opts.dialEphemeral(ctx, network, address, opts.Timeout)
+		}
+		ps.Service = opts.Services.Detect(ctx, dial, network, host, ps.Port, opts.Timeout)
+	}
+}
+
+// Options controls how a scan is executed
+type Options struct {
+	Network         string
+	Timeout         time.Duration
+	Concurrency     int
+	HostConcurrency int
+	Banner          bool
+	// Services enables service detection for open TCP ports when set
+	Services *service.DB
+	// Limiter paces all connections of the scan, nil scans at full speed
+	Limiter *util.Limiter
+	// Proxy connects to the ports through a SOCKS5 or HTTP CONNECT proxy,
+	// which also resolves the host names. Its answers become port states.
+	Proxy *util.Proxy
+	// Source binds all connections to a local address, interface and port,
+	// nil uses the default route
+	Source *util.Source
+	// Discovery checks whether a host is up before its ports are scanned,
+	// nil scans every host
+	Discovery *Discovery
+	// AllAddresses scans every address the host resolves to in the address
+	// family of Network separately instead of dialing the host name
+	AllAddresses bool
+	// Retries repeats probes without answer (filtered, open|filtered)
+	Retries int
+	// Adaptive derives the probe timeout of every host from the round trip
+	// times of its answers, Timeout is the initial and maximum timeout
+	Adaptive bool
+	// Syn sends SYN packets on a raw socket instead of connecting (half-open
+	// scan). It needs root or CAP_NET_RAW, Linux and IPv4.
+	Syn bool
+	// UDPPorts are probed over UDP in addition to the ports of a TCP
+	// Network, using the same address family, e.g. udp4 for tcp4
+	UDPPorts []int
+
+	// OnPortState is called whenever a probe finished, in the order the
+	// probes finish
+	OnPortState func(host string, ps *PortState)
+	// OnResult is called whenever all ports of a host are scanned, in the
+	// order of the targets
+	OnResult func(res *ScanResult)
+
+	// Raw socket of the SYN scan while streaming
+	syn *synScanner
+	// Global semaphore while streaming, every probe holds one of its slots
+	sem chan struct{}
+}
+
+func NewOptions(network string, timeout time.Duration, concurrency, hostConcurrency int) *Options {
+	return &Options{
+		Network:         network,
+		Timeout:         timeout,
+		Concurrency:     concurrency,
+		HostConcurrency: hostConcurrency,
+	}
+}
+
+// Scan all ports of a single host by dialing target, its name or one of its
+// addresses, using at most opts.HostConcurrency workers. Every probe
+// additionally acquires a slot of the global semaphore.
+func scanHost(ctx context.Context, host string, target string, ports []int, opts *Options, onPortState func(string, *PortState)) (*[]PortState, *HostTiming) {
+	networks := make([]string, len(ports), len(ports)+len(opts.UDPPorts))
+	for i := range networks {
+		networks[i] = opts.Network
+	}
+	if len(opts.UDPPorts) > 0 && Protocol(opts.Network) == PROTO_TCP {
+		udp := PROTO_UDP + strings.TrimPrefix(opts.Network, PROTO_TCP)
+		ports = slices.Concat(ports, opts.UDPPorts)
+		for range opts.UDPPorts {
+			networks = append(networks, udp)
+		}
+	}
+
+	states := make([]PortState, len(ports))
+	rtt := newRTTEstimator(opts.Timeout, opts.Adaptive)
+
+	workers := min(max(opts.HostConcurrency, 1), len(ports))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				opts.sem <- struct{}{}
+				states[i] = probe(ctx, target, ports[i], networks[i], opts, rtt)
+				<-opts.sem
+				onPortState(host, &states[i])
+			}
+		}()
+	}
+
+	for i := range ports {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return &states, rtt.snapshot()
+}
+
+// Look up the addresses of the host. With AllAddresses only the addresses
+// the network can dial are looked up, like the dialer does, the SYN scan
+// only looks up IPv4 addresses.
+func resolve(ctx context.Context, host string, opts *Options) ([]string, error) {
+	if !opts.AllAddresses && !opts.Syn {
+		return net.DefaultResolver.LookupHost(ctx, host)
+	}
 
-	for i, h := range hl.Hosts {
-		res := &results[i]
-		res.Host = h
-		res.PortStates = &[]PortState{}
+	family := "ip"
+	if opts.Syn {
+		family = "ip4"
+	} else if strings.HasSuffix(opts.Network, "4") || strings.HasSuffix(opts.Network, "6") {
+		family += opts.Network[len(opts.Network)-1:]
+	}
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, family, host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, len(ips))
+	for i, ip := range ips {
+		addrs[i] = ip.Unmap().String()
+	}
+	return addrs, nil
+}
 
-		if _, err := net.LookupHost(h); err != nil {
+// Resolve the host, skip it if the discovery finds it down and scan it by
+// name, or every address of it with AllAddresses
+func scanTarget(ctx context.Context, res *ScanResult, ports []int, opts *Options, onPortState func(string, *PortState)) {
+	res.PortStates = &[]PortState{}
+	// Behind a proxy the host name may only resolve on the other side
+	addrs := []string{res.Host}
+	if opts.Proxy == nil || opts.AllAddresses {
+		var err error
+		if addrs, err = resolve(ctx, res.Host, opts); err != nil {
 			res.NotFound = true
-			continue
+			return
+		}
+		res.Addr = addrs[0]
+	}
+
+	if opts.Discovery != nil {
+		// A canceled discovery says nothing about the host
+		up, reason := opts.Discovery.discover(ctx, addrs, opts)
+		if up || ctx.Err() == nil {
+			res.Reason = reason
+		}
+		if !up && ctx.Err() == nil {
+			res.Down = true
+			return
+		}
+	}
+
+	if !opts.AllAddresses {
+		// Raw sockets can't dial host names
+		target := res.Host
+		if opts.Syn {
+			target = res.Addr
+		}
+		res.PortStates, res.Timing = scanHost(ctx, res.Host, target, ports, opts, onPortState)
+		return
+	}
+
+	res.Addresses = []AddressResult{}
+	for _, a := range addrs {
+		addr := AddressResult{Addr: a}
+		addr.PortStates, addr.Timing = scanHost(ctx, res.Host, addr.Addr, ports, opts, onPortState)
+		res.Addresses = append(res.Addresses, addr)
+	}
+}
+
+// Probe a port and retry as long as the probe gets no answer. Every answer
+// feeds the round trip time estimator and every probe without answer backs
+// its timeout off.
+func probe(ctx context.Context, host string, port int, network string, opts *Options, rtt *rttEstimator) PortState {
+	var ps *PortState
+	for attempt := 0; attempt <= opts.Retries; attempt++ {
+		if attempt > 0 && ctx.Err() != nil {
+			break
+		}
+		ps = scan(ctx, host, port, network, rtt.timeout(), opts)
+		ps.Retries = attempt
+		if ps.rtt > 0 {
+			rtt.sample(ps.rtt)
+		}
+		if ps.Open != FILTERED && ps.Open != OPEN_FILTERED {
+			break
+		}
+		rtt.backoff()
+	}
+	return *ps
+}
+
+// A host waiting to be scanned, done is closed once the result is complete
+type hostJob struct {
+	res  *ScanResult
+	done chan struct{}
+}
+
+// Stream scans all targets and hands every port state and every finished
+// host to the callbacks of the options instead of keeping the results.
+// Hosts and ports are probed concurrently, but OnResult is called in the
+// order of the targets and all callbacks are serialized. Once the context is
+// canceled no further hosts are started, probes which are still pending end
+// with the ERROR state and the context error is returned. A SYN scan fails
+// with ErrSyn if the raw socket can't be opened.
+func Stream(ctx context.Context, targets iter.Seq[string], ports []int, opts *Options) error {
+	streamOpts := *opts
+	opts = &streamOpts
+	if opts.Syn {
+		syn, err := newSynScanner(opts)
+		if err != nil {
+			return err
 		}
+		defer syn.close()
+		opts.syn = syn
+	}
 
-		for _, p := range *ports {
-			ps := scan(h, p, network, timeout)
-			*res.PortStates = append(*res.PortStates, *ps)
+	var mu sync.Mutex
+	onPortState := func(host string, ps *PortState) {
+		if opts.OnPortState != nil {
+			mu.Lock()
+			defer mu.Unlock()
+			opts.OnPortState(host, ps)
 		}
+	}
 
+	concurrency := max(opts.Concurrency, 1)
+	opts.sem = make(chan struct{}, concurrency)
+
+	jobs := make(chan *hostJob)
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				res := job.res
+				res.StartTime = time.Now()
+				scanTarget(ctx, res, ports, opts, onPortState)
+				res.EndTime = time.Now()
+				close(job.done)
+			}
+		}()
+	}
+
+	// Hand the results over in the order of the targets. Every job enters
+	// the queue before it is sent to the workers, so the queue never waits
+	// for a host which has not been started.
+	queue := make(chan *hostJob, concurrency)
+	emitted := make(chan struct{})
+	go func() {
+		defer close(emitted)
+		for job := range queue {
+			<-job.done
+			if opts.OnResult != nil {
+				mu.Lock()
+				opts.OnResult(job.res)
+				mu.Unlock()
+			}
+		}
+	}()
+
+	for h := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		job := &hostJob{res: NewScanResult(h), done: make(chan struct{})}
+		queue <- job
+		jobs <- job
+	}
+	close(jobs)
+	close(queue)
+	wg.Wait()
+	<-emitted
+
+	return ctx.Err()
+}
+
+// Run the scan process for all targets and collect the results in the
+// order of the targets and ports. Callbacks of the options are still called.
+// Once the context is canceled the partial results are returned together
+// with the context error.
+func Run(ctx context.Context, targets iter.Seq[string], ports []int, opts *Options) ([]ScanResult, error) {
+	results := []ScanResult{}
+	collect := *opts
+	collect.OnResult = func(res *ScanResult) {
+		results = append(results, *res)
+		if opts.OnResult != nil {
+			opts.OnResult(res)
+		}
 	}
 
-	return &results
+	err := Stream(ctx, targets, ports, &collect)
+	return results, err
 }
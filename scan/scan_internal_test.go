@@ -0,0 +1,159 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package scan
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	dialErr := func(err error) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", err)}
+	}
+
+	testCases := []struct {
+		name   string
+		err    error
+		state  state
+		reason string
+	}{
+		{"Timeout", &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}, FILTERED, REASON_NO_RESPONSE},
+		{"Refused", dialErr(syscall.ECONNREFUSED), CLOSED, REASON_REFUSED},
+		{"HostUnreachable", dialErr(syscall.EHOSTUNREACH), UNREACHABLE, REASON_HOST_UNREACH},
+		{"NetUnreachable", dialErr(syscall.ENETUNREACH), UNREACHABLE, REASON_NET_UNREACH},
+		{"PermissionDenied", dialErr(syscall.EACCES), ERROR, REASON_PERMISSION},
+		{"TooManyFiles", dialErr(syscall.EMFILE), ERROR, REASON_TOO_MANY_FILES},
+		{"Other", dialErr(errors.New("boom")), ERROR, "connect: boom"},
+		{"Canceled", context.Canceled, ERROR, context.Canceled.Error()},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, reason := classify(tc.err)
+			if s != tc.state {
+				t.Errorf("Expected %s, got %s instead", &tc.state, &s)
+			}
+			if reason != tc.reason {
+				t.Errorf("Expected %q, got %q instead", tc.reason, reason)
+			}
+		})
+	}
+}
+
+func TestUDPPayload(t *testing.T) {
+	testCases := []struct {
+		port int
+		size int
+	}{
+		{53, len(dnsPayload)},
+		{123, 48},
+		{161, int(snmpPayload[1]) + 2},
+		{9999, 0},
+	}
+
+	for _, tc := range testCases {
+		if size := len(udpPayload(tc.port)); size != tc.size {
+			t.Errorf("Expected %d bytes for port %d, got %d instead", tc.size, tc.port, size)
+		}
+	}
+}
+
+func TestSanitizeBanner(t *testing.T) {
+	testCases := []struct {
+		name     string
+		raw      []byte
+		expected string
+	}{
+		{"Plain", []byte("SSH-2.0-OpenSSH_9.6\r\n"), "SSH-2.0-OpenSSH_9.6"},
+		{"MultiLine", []byte("HTTP/1.0 200 OK\r\nServer: nginx\r\n\r\n"), "HTTP/1.0 200 OK Server: nginx"},
+		{"Binary", []byte{'J', 0x00, 0x01, 'x'}, `J\x00\x01x`},
+		{"Empty", []byte{}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if out := sanitizeBanner(tc.raw); out != tc.expected {
+				t.Errorf("Expected %q, got %q instead", tc.expected, out)
+			}
+		})
+	}
+}
+
+func TestRTTEstimator(t *testing.T) {
+	rtt := newRTTEstimator(time.Second, true)
+	if rtt.timeout() != time.Second {
+		t.Errorf("Expected %s before the first sample, got %s instead", time.Second, rtt.timeout())
+	}
+
+	rtt.sample(100 * time.Millisecond)
+	// srtt 100ms, rttvar 50ms
+	if expected := 300 * time.Millisecond; rtt.timeout() != expected {
+		t.Errorf("Expected %s, got %s instead", expected, rtt.timeout())
+	}
+
+	rtt.sample(100 * time.Millisecond)
+	// srtt 100ms, rttvar 37.5ms
+	if expected := 250 * time.Millisecond; rtt.timeout() != expected {
+		t.Errorf("Expected %s, got %s instead", expected, rtt.timeout())
+	}
+
+	rtt.backoff()
+	rtt.backoff()
+	if rtt.timeout() != time.Second {
+		t.Errorf("Expected backoff up to %s, got %s instead", time.Second, rtt.timeout())
+	}
+
+	rtt.sample(time.Microsecond)
+	if timing := rtt.snapshot(); timing.Samples != 3 || timing.Timeout < MIN_TIMEOUT {
+		t.Errorf("Unexpected timing %+v", timing)
+	}
+
+	fixed := newRTTEstimator(time.Second, false)
+	fixed.sample(time.Millisecond)
+	fixed.backoff()
+	if fixed.timeout() != time.Second || fixed.snapshot() != nil {
+		t.Errorf("Expected fixed timeout of %s, got %s instead", time.Second, fixed.timeout())
+	}
+}
+
+func TestTCPSegment(t *testing.T) {
+	src, dst := netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("192.0.2.2")
+	seg := tcpSegment(src, dst, 40000, 443, 0x13371337, tcpSyn)
+
+	if len(seg) != tcpHeaderLen || seg[13] != tcpSyn || binary.BigEndian.Uint16(seg[2:]) != 443 {
+		t.Fatalf("Unexpected segment %x", seg)
+	}
+	// The checksum over pseudo header and segment including its checksum is 0
+	pseudo := append(src.AsSlice(), dst.AsSlice()...)
+	pseudo = append(pseudo, 0, 6, 0, tcpHeaderLen)
+	if sum := checksum(append(pseudo, seg...)); sum != 0 {
+		t.Errorf("Expected checksum to verify, got %#x instead", sum)
+	}
+}
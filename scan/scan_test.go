@@ -22,13 +22,18 @@ THE SOFTWARE.
 package scan_test
 
 import (
+	"context"
+	"errors"
 	"net"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/soner3/net-scan/host"
 	"github.com/soner3/net-scan/scan"
+	"github.com/soner3/net-scan/scan/service"
+	"github.com/soner3/net-scan/util"
 )
 
 func TestRun(t *testing.T) {
@@ -38,7 +43,7 @@ func TestRun(t *testing.T) {
 	}{
 		{"PortOpen", "open"},
 		{"PortClosed", "closed"},
-		{"PortTimeout", "timeout"},
+		{"PortTimeout", "filtered"},
 	}
 
 	localhost := "localhost"
@@ -71,36 +76,36 @@ func TestRun(t *testing.T) {
 
 	}
 
-	localhostRes := scan.Run(hl, &ports, "tcp", time.Second)
+	localhostRes, _ := scan.Run(context.Background(), hl.Targets(), ports, scan.NewOptions("tcp", time.Second, 10, 10))
 	hl.Remove(localhost)
 	hl.Add(timeoutHost)
-	timeoutRes := scan.Run(hl, &ports, "tcp", time.Second)
+	timeoutRes, _ := scan.Run(context.Background(), hl.Targets(), ports, scan.NewOptions("tcp", time.Second, 10, 10))
 
-	if len(*(*localhostRes)[0].PortStates) != 2 {
-		t.Errorf("Expected %d, got %d instead", 2, len(*localhostRes))
+	if len(*localhostRes[0].PortStates) != 2 {
+		t.Errorf("Expected %d, got %d instead", 2, len(localhostRes))
 	}
 
-	if len(*(*timeoutRes)[0].PortStates) != 2 {
-		t.Errorf("Expected %d, got %d instead", 2, len(*timeoutRes))
+	if len(*timeoutRes[0].PortStates) != 2 {
+		t.Errorf("Expected %d, got %d instead", 2, len(timeoutRes))
 	}
 
-	if (*localhostRes)[0].Host != localhost {
-		t.Errorf("Expected %s, got %s instead", localhost, (*localhostRes)[0].Host)
+	if localhostRes[0].Host != localhost {
+		t.Errorf("Expected %s, got %s instead", localhost, localhostRes[0].Host)
 	}
 
-	if (*timeoutRes)[0].Host != timeoutHost {
-		t.Errorf("Expected %s, got %s instead", localhost, (*localhostRes)[0].Host)
+	if timeoutRes[0].Host != timeoutHost {
+		t.Errorf("Expected %s, got %s instead", localhost, localhostRes[0].Host)
 	}
 
-	if (*localhostRes)[0].NotFound {
-		t.Errorf("Expected host %s to be found", (*localhostRes)[0].Host)
+	if localhostRes[0].NotFound {
+		t.Errorf("Expected host %s to be found", localhostRes[0].Host)
 	}
 
-	if (*timeoutRes)[0].NotFound {
-		t.Errorf("Expected host %s to be found", (*localhostRes)[0].Host)
+	if timeoutRes[0].NotFound {
+		t.Errorf("Expected host %s to be found", localhostRes[0].Host)
 	}
 
-	for _, res := range *localhostRes {
+	for _, res := range localhostRes {
 		if (*res.PortStates)[0].Open.String() != "open" {
 			t.Errorf("Expected %s, got %s instead", "open", (*res.PortStates)[0].Open.String())
 		}
@@ -109,10 +114,10 @@ func TestRun(t *testing.T) {
 		}
 	}
 
-	for _, res := range *timeoutRes {
+	for _, res := range timeoutRes {
 		for _, ps := range *res.PortStates {
-			if ps.Open.String() != "timeout" {
-				t.Errorf("Expected %s, got %s instead", "timeout", ps.Open.String())
+			if ps.Open.String() != "filtered" {
+				t.Errorf("Expected %s, got %s instead", "filtered", ps.Open.String())
 			}
 		}
 	}
@@ -128,18 +133,470 @@ func TestHostNotFound(t *testing.T) {
 		{"unknown", false},
 	}
 	hl := host.NewHostList()
-	ports := &[]int{80}
+	ports := []int{80}
 
 	for _, tc := range testCases {
 		hl.Add(tc.hostname)
 	}
 
-	res := scan.Run(hl, ports, "tcp", 1000)
+	res, _ := scan.Run(context.Background(), hl.Targets(), ports, scan.NewOptions("tcp", 1000, 10, 10))
 
 	for i, tc := range testCases {
-		if (*res)[i].NotFound != !tc.found {
-			t.Errorf("Expected %v, got %v instead", !tc.found, (*res)[i].NotFound)
+		if res[i].NotFound != !tc.found {
+			t.Errorf("Expected %v, got %v instead", !tc.found, res[i].NotFound)
 		}
 	}
 
 }
+
+func TestRunKeepsPortOrder(t *testing.T) {
+	hl := host.NewHostList()
+	hl.Add("localhost")
+	hl.Add("127.0.0.1")
+
+	ports := []int{}
+	open := map[int]bool{}
+	for i := range 20 {
+		ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", "0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		port := ln.Addr().(*net.TCPAddr).Port
+		ports = append(ports, port)
+		open[port] = i%2 == 0
+		if i%2 != 0 {
+			ln.Close()
+		}
+	}
+
+	res, _ := scan.Run(context.Background(), hl.Targets(), ports, scan.NewOptions("tcp4", time.Second, 3, 2))
+
+	if len(res) != len(hl.Hosts) {
+		t.Fatalf("Expected %d, got %d instead", len(hl.Hosts), len(res))
+	}
+
+	for i, r := range res {
+		if r.Host != hl.Hosts[i] {
+			t.Errorf("Expected %s, got %s instead", hl.Hosts[i], r.Host)
+		}
+		for j, ps := range *r.PortStates {
+			if ps.Port != ports[j] {
+				t.Errorf("Expected %d, got %d instead", ports[j], ps.Port)
+			}
+			if open[ps.Port] != (ps.Open == scan.OPEN) {
+				t.Errorf("Expected open=%v for port %d, got %s instead", open[ps.Port], ps.Port, ps.Open.String())
+			}
+		}
+	}
+}
+
+func TestRunBanner(t *testing.T) {
+	serve := func(handle func(net.Conn)) int {
+		ln, err := net.Listen("tcp4", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { ln.Close() })
+		go func() {
+			for {
+				con, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				handle(con)
+				con.Close()
+			}
+		}()
+		return ln.Addr().(*net.TCPAddr).Port
+	}
+
+	greeting := serve(func(con net.Conn) {
+		con.Write([]byte("SSH-2.0-Test\r\n"))
+	})
+	silent := serve(func(con net.Conn) {
+		buf := make([]byte, 64)
+		if n, _ := con.Read(buf); n > 0 {
+			con.Write([]byte("ECHO " + string(buf[:n])))
+		}
+	})
+
+	hl := host.NewHostList()
+	hl.Add("127.0.0.1")
+	ports := []int{greeting, silent}
+	opts := scan.NewOptions("tcp4", 200*time.Millisecond, 10, 10)
+	opts.Banner = true
+	res, _ := scan.Run(context.Background(), hl.Targets(), ports, opts)
+
+	expected := []string{"SSH-2.0-Test", "ECHO"}
+	for i, ps := range *res[0].PortStates {
+		if ps.Banner != expected[i] {
+			t.Errorf("Expected %q, got %q instead", expected[i], ps.Banner)
+		}
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	hl := host.NewHostList()
+	hl.Add("10.0.0.0/24")
+	res, err := scan.Run(ctx, hl.Targets(), []int{80}, scan.NewOptions("tcp", time.Second, 10, 10))
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %q, got %q instead", context.Canceled, err)
+	}
+	if len(res) != 0 {
+		t.Errorf("Expected %d, got %d instead", 0, len(res))
+	}
+}
+
+func TestStream(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	ports := []int{ln.Addr().(*net.TCPAddr).Port, 1}
+
+	hl := host.NewHostList()
+	hl.Add("127.0.0.1")
+	hl.Add("localhost")
+	hl.Add("unknown")
+
+	portStates := 0
+	hosts := []string{}
+	opts := scan.NewOptions("tcp4", time.Second, 3, 2)
+	opts.OnPortState = func(host string, ps *scan.PortState) {
+		portStates++
+	}
+	opts.OnResult = func(res *scan.ScanResult) {
+		hosts = append(hosts, res.Host)
+	}
+
+	if err := scan.Stream(context.Background(), hl.Targets(), ports, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// The unknown host is never probed
+	if portStates != 2*len(ports) {
+		t.Errorf("Expected %d, got %d instead", 2*len(ports), portStates)
+	}
+
+	// Results arrive in the order of the targets
+	if strings.Join(hosts, ",") != strings.Join(hl.Hosts, ",") {
+		t.Errorf("Expected %v, got %v instead", hl.Hosts, hosts)
+	}
+}
+
+func TestRunLimiter(t *testing.T) {
+	testCases := []struct {
+		name      string
+		maxRate   float64
+		hostDelay time.Duration
+		minimum   time.Duration
+	}{
+		// 5 connects at 20 per second need 4 intervals of 50ms
+		{"MaxRate", 20, 0, 200 * time.Millisecond},
+		// 5 connects to the same host need 4 delays of 60ms
+		{"HostDelay", 0, 60 * time.Millisecond, 240 * time.Millisecond},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hl := host.NewHostList()
+			hl.Add("127.0.0.1")
+
+			opts := scan.NewOptions("tcp4", time.Second, 10, 10)
+			limiter, err := util.NewLimiter(tc.maxRate, tc.hostDelay, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts.Limiter = limiter
+
+			start := time.Now()
+			res, _ := scan.Run(context.Background(), hl.Targets(), []int{1, 2, 3, 4, 5}, opts)
+			if elapsed := time.Since(start); elapsed < tc.minimum {
+				t.Errorf("Expected at least %s, got %s instead", tc.minimum, elapsed)
+			}
+			if len(*res[0].PortStates) != 5 {
+				t.Errorf("Expected %d, got %d instead", 5, len(*res[0].PortStates))
+			}
+		})
+	}
+}
+
+func TestRunLimiterSlots(t *testing.T) {
+	hl := host.NewHostList()
+	for i := 1; i <= 8; i++ {
+		hl.Add("127.0.0." + strconv.Itoa(i))
+	}
+
+	opts := scan.NewOptions("tcp4", time.Second, 4, 10)
+	limiter, err := util.NewLimiter(0, 20*time.Millisecond, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Limiter = limiter
+
+	// Two rounds of 4 hosts need 9 delays of 20ms per host. Probes waiting
+	// out the delay of their host must not keep the other hosts from their
+	// slots, which takes about 3 times as long.
+	ports := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	start := time.Now()
+	res, _ := scan.Run(context.Background(), hl.Targets(), ports, opts)
+	if elapsed := time.Since(start); elapsed > 700*time.Millisecond {
+		t.Errorf("Expected at most %s, got %s instead", 700*time.Millisecond, elapsed)
+	}
+	for _, r := range res {
+		if len(*r.PortStates) != len(ports) {
+			t.Errorf("Expected %d, got %d instead", len(ports), len(*r.PortStates))
+		}
+	}
+}
+
+func TestRunAdaptiveTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	hl := host.NewHostList()
+	hl.Add("127.0.0.1")
+	opts := scan.NewOptions("tcp4", 5*time.Second, 10, 1)
+	opts.Adaptive = true
+	opts.Retries = 1
+	res, _ := scan.Run(context.Background(), hl.Targets(), []int{ln.Addr().(*net.TCPAddr).Port, 1, 2}, opts)
+
+	// Open and closed ports both answer, a loopback answer is far below
+	// the lower bound of the timeout
+	timing := res[0].Timing
+	if timing == nil || timing.Samples != 3 || timing.Timeout != scan.MIN_TIMEOUT {
+		t.Errorf("Unexpected timing %+v", timing)
+	}
+	for _, ps := range *res[0].PortStates {
+		if ps.Retries != 0 {
+			t.Errorf("Expected no retries for port %d, got %d instead", ps.Port, ps.Retries)
+		}
+	}
+
+	opts.Adaptive = false
+	res, _ = scan.Run(context.Background(), hl.Targets(), []int{1}, opts)
+	if res[0].Timing != nil {
+		t.Errorf("Expected no timing without adaptive timeouts, got %+v instead", res[0].Timing)
+	}
+}
+
+func TestRunAdaptiveTimeoutLimiter(t *testing.T) {
+	hl := host.NewHostList()
+	hl.Add("127.0.0.1")
+	opts := scan.NewOptions("tcp4", 5*time.Second, 10, 5)
+	opts.Adaptive = true
+	limiter, err := util.NewLimiter(0, 50*time.Millisecond, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Limiter = limiter
+
+	// All probes start at once and queue up to 200ms in the limiter, only
+	// the loopback round trips may end up in the estimates
+	res, _ := scan.Run(context.Background(), hl.Targets(), []int{1, 2, 3, 4, 5}, opts)
+	timing := res[0].Timing
+	if timing == nil || timing.Samples != 5 || timing.SRTT > 20*time.Millisecond || timing.Timeout != scan.MIN_TIMEOUT {
+		t.Errorf("Expected estimates without the limiter wait, got %+v instead", timing)
+	}
+}
+
+func TestRunAllAddresses(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	hl := host.NewHostList()
+	hl.Add("localhost")
+	hl.Add("unknown")
+	opts := scan.NewOptions("tcp4", time.Second, 10, 10)
+	opts.AllAddresses = true
+	res, _ := scan.Run(context.Background(), hl.Targets(), []int{port}, opts)
+
+	// tcp4 only resolves the IPv4 addresses of the host
+	found := res[0]
+	if len(found.Addresses) != 1 || found.Addresses[0].Addr != "127.0.0.1" || found.Addr != "127.0.0.1" {
+		t.Fatalf("Expected the address 127.0.0.1, got %+v instead", found.Addresses)
+	}
+	if len(*found.PortStates) != 0 {
+		t.Errorf("Expected the ports below the addresses, got %v instead", *found.PortStates)
+	}
+	states := *found.Addresses[0].PortStates
+	if len(states) != 1 || states[0].Open != scan.OPEN {
+		t.Errorf("Expected port %d open, got %+v instead", port, states)
+	}
+	if len(found.AddressResults()) != 1 {
+		t.Errorf("Expected %d, got %d instead", 1, len(found.AddressResults()))
+	}
+
+	if !res[1].NotFound || res[1].Addresses != nil {
+		t.Errorf("Expected unknown host to be not found, got %+v instead", res[1])
+	}
+}
+
+func TestRunDiscovery(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	testCases := []struct {
+		name      string
+		discovery *scan.Discovery
+		down      bool
+		reason    string
+	}{
+		{"TCP", &scan.Discovery{Methods: []string{scan.DISCOVERY_TCP}, Ports: []int{port}, Timeout: time.Second}, false, scan.REASON_CONNECTED},
+		// ARP only answers on local segments, never for the loopback address
+		{"ARPLoopback", &scan.Discovery{Methods: []string{scan.DISCOVERY_ARP}, Timeout: time.Second}, true, scan.REASON_NO_RESPONSE},
+		{"Skip", nil, false, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hl := host.NewHostList()
+			hl.Add("127.0.0.1")
+			opts := scan.NewOptions("tcp4", time.Second, 10, 10)
+			opts.Discovery = tc.discovery
+			res, _ := scan.Run(context.Background(), hl.Targets(), []int{port}, opts)
+
+			if res[0].Down != tc.down || res[0].Reason != tc.reason {
+				t.Errorf("Expected down %v (%q), got %v (%q) instead", tc.down, tc.reason, res[0].Down, res[0].Reason)
+			}
+			expected := 1
+			if tc.down {
+				expected = 0
+			}
+			if len(*res[0].PortStates) != expected {
+				t.Errorf("Expected %d ports, got %d instead", expected, len(*res[0].PortStates))
+			}
+		})
+	}
+}
+
+func TestRunSource(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	remote := make(chan net.Addr, 1)
+	go func() {
+		con, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		remote <- con.RemoteAddr()
+		con.Close()
+	}()
+
+	free, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourcePort := free.Addr().(*net.TCPAddr).Port
+	free.Close()
+
+	source, err := util.NewSource("127.0.0.1", "", sourcePort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hl := host.NewHostList()
+	hl.Add("127.0.0.1")
+	opts := scan.NewOptions("tcp4", time.Second, 10, 10)
+	opts.Source = source
+	res, _ := scan.Run(context.Background(), hl.Targets(), []int{ln.Addr().(*net.TCPAddr).Port}, opts)
+
+	if ps := (*res[0].PortStates)[0]; ps.Open != scan.OPEN {
+		t.Fatalf("Expected open, got %s (%s) instead", ps.Open.String(), ps.Reason)
+	}
+	if addr := (<-remote).(*net.TCPAddr); addr.Port != sourcePort {
+		t.Errorf("Expected source port %d, got %d instead", sourcePort, addr.Port)
+	}
+}
+
+func TestRunSourcePortReuse(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			con, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer con.Close()
+				con.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+				con.Read(make([]byte, 1))
+			}()
+		}
+	}()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	free, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourcePort := free.Addr().(*net.TCPAddr).Port
+	free.Close()
+
+	source, err := util.NewSource("127.0.0.1", "", sourcePort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := service.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Discovery and service detection connect to the scanned port as well,
+	// the fixed source port can only be used by the probe itself
+	testCases := []struct {
+		name      string
+		discovery *scan.Discovery
+		services  *service.DB
+	}{
+		{"Discovery", &scan.Discovery{Methods: []string{scan.DISCOVERY_TCP}, Ports: []int{port}, Timeout: time.Second}, nil},
+		{"Services", nil, db},
+		{"Both", &scan.Discovery{Methods: []string{scan.DISCOVERY_TCP}, Ports: []int{port}, Timeout: time.Second}, db},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hl := host.NewHostList()
+			hl.Add("127.0.0.1")
+			opts := scan.NewOptions("tcp4", time.Second, 10, 10)
+			opts.Source = source
+			opts.Discovery = tc.discovery
+			opts.Services = tc.services
+			res, _ := scan.Run(context.Background(), hl.Targets(), []int{port}, opts)
+
+			if res[0].Down {
+				t.Fatalf("Expected host up, got down (%s) instead", res[0].Reason)
+			}
+			ps := (*res[0].PortStates)[0]
+			if ps.Open != scan.OPEN {
+				t.Fatalf("Expected open, got %s (%s) instead", ps.Open.String(), ps.Reason)
+			}
+			if tc.services != nil && (ps.Service == nil || ps.Service.Name != "ssh") {
+				t.Errorf("Expected service ssh, got %v instead", ps.Service)
+			}
+		})
+	}
+}
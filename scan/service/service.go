@@ -0,0 +1,402 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package service
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed services.db
+var defaultDB string
+
+const responseSize = 4096
+
+var ErrSyntax = errors.New("invalid service database")
+
+// DialFunc opens the connections used to send probes
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// Service identified on an open port
+type Service struct {
+	Name    string `json:"name"`
+	Product string `json:"product,omitempty"`
+	Version string `json:"version,omitempty"`
+	Info    string `json:"info,omitempty"`
+}
+
+func (s *Service) String() string {
+	out := s.Name
+	for _, part := range []string{s.Product, s.Version} {
+		if part != "" {
+			out += " " + part
+		}
+	}
+	if s.Info != "" {
+		out += fmt.Sprintf(" (%s)", s.Info)
+	}
+	return out
+}
+
+type match struct {
+	service string
+	pattern *regexp.Regexp
+	product string
+	version string
+	info    string
+}
+
+// Probe is a payload sent to a port and the matches run against the answer
+type Probe struct {
+	Name    string
+	Payload []byte
+	Ports   []int
+	matches []match
+}
+
+// DB holds all probes in the order they are tried
+type DB struct {
+	Probes []*Probe
+}
+
+// Load the embedded database and merge the file into it, if one is given.
+// Matches from the file take precedence over the embedded ones.
+func Load(filename string) (*DB, error) {
+	db := &DB{}
+	if err := db.parse(strings.NewReader(defaultDB)); err != nil {
+		return nil, err
+	}
+	if filename == "" {
+		return db, nil
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := db.parse(f); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return db, nil
+}
+
+// Parse probes and matches and merge them into the database
+func (db *DB) parse(r io.Reader) error {
+	var probe *Probe
+	var added []match
+	flush := func() {
+		if probe != nil {
+			probe.matches = append(added, probe.matches...)
+		}
+		added = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		keyword, rest, _ := strings.Cut(line, " ")
+		var err error
+		switch keyword {
+		case "Probe":
+			flush()
+			probe, err = db.parseProbe(rest)
+		case "ports":
+			if probe == nil {
+				err = errors.New("ports outside of a probe")
+				break
+			}
+			var ports []int
+			ports, err = parsePorts(rest)
+			probe.Ports = append(probe.Ports, ports...)
+		case "match":
+			if probe == nil {
+				err = errors.New("match outside of a probe")
+				break
+			}
+			var m *match
+			m, err = parseMatch(rest)
+			if m != nil {
+				added = append(added, *m)
+			}
+		default:
+			err = fmt.Errorf("unknown keyword %q", keyword)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%w: line %d: %s", ErrSyntax, lineNo, err)
+		}
+	}
+	flush()
+
+	return scanner.Err()
+}
+
+// Parse "<name> q|<payload>|". A probe with a known name is reused so
+// files can add matches to the embedded probes.
+func (db *DB) parseProbe(s string) (*Probe, error) {
+	name, rest, _ := strings.Cut(s, " ")
+	if name == "" {
+		return nil, errors.New("probe without name")
+	}
+	if !strings.HasPrefix(rest, "q") {
+		return nil, fmt.Errorf("probe %s without payload", name)
+	}
+	raw, _, err := delimited(rest[1:])
+	if err != nil {
+		return nil, err
+	}
+	payload, err := unescape(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range db.Probes {
+		if p.Name == name {
+			p.Payload = payload
+			return p, nil
+		}
+	}
+	p := &Probe{Name: name, Payload: payload}
+	db.Probes = append(db.Probes, p)
+	return p, nil
+}
+
+// Parse "<service> m|<regex>|[flags] [p/../] [v/../] [i/../]"
+func parseMatch(s string) (*match, error) {
+	name, rest, _ := strings.Cut(s, " ")
+	if name == "" || !strings.HasPrefix(rest, "m") {
+		return nil, errors.New("match needs a service and a pattern")
+	}
+	expr, rest, err := delimited(rest[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	flags, rest, _ := strings.Cut(rest, " ")
+	for _, f := range flags {
+		if f != 'i' && f != 's' {
+			return nil, fmt.Errorf("unknown regex flag %q", f)
+		}
+	}
+	if flags != "" {
+		expr = fmt.Sprintf("(?%s)%s", flags, expr)
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &match{service: name, pattern: pattern}
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		key := rest[0]
+		var value string
+		value, rest, err = delimited(rest[1:])
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case 'p':
+			m.product = value
+		case 'v':
+			m.version = value
+		case 'i':
+			m.info = value
+		default:
+			return nil, fmt.Errorf("unknown match field %q", key)
+		}
+	}
+	return m, nil
+}
+
+// Parse a comma separated list of ports and port ranges
+func parsePorts(s string) ([]int, error) {
+	var ports []int
+	for _, part := range strings.Split(s, ",") {
+		start, end, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		first, err := strconv.Atoi(start)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(end); err != nil {
+				return nil, err
+			}
+		}
+		for p := first; p <= last; p++ {
+			ports = append(ports, p)
+		}
+	}
+	return ports, nil
+}
+
+// Read a value enclosed by the delimiter at the start of s
+func delimited(s string) (string, string, error) {
+	if s == "" {
+		return "", "", errors.New("missing delimiter")
+	}
+	end := strings.IndexByte(s[1:], s[0])
+	if end < 0 {
+		return "", "", fmt.Errorf("unterminated value %q", s)
+	}
+	return s[1 : end+1], s[end+2:], nil
+}
+
+// Resolve the escape sequences of a probe payload
+func unescape(s string) ([]byte, error) {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			out = append(out, s[i])
+			continue
+		}
+		if i++; i == len(s) {
+			return nil, errors.New("trailing backslash")
+		}
+		switch s[i] {
+		case 'r':
+			out = append(out, '\r')
+		case 'n':
+			out = append(out, '\n')
+		case 't':
+			out = append(out, '\t')
+		case '0':
+			out = append(out, 0)
+		case '\\':
+			out = append(out, '\\')
+		case 'x':
+			if i+2 >= len(s) {
+				return nil, errors.New("short hex escape")
+			}
+			b, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, byte(b))
+			i += 2
+		default:
+			return nil, fmt.Errorf("unknown escape \\%c", s[i])
+		}
+	}
+	return out, nil
+}
+
+// Check if the probe should be sent to the port
+func (p *Probe) applies(port int) bool {
+	return len(p.Ports) == 0 || slices.Contains(p.Ports, port)
+}
+
+// Send the probe and return the first bytes of the answer
+func (p *Probe) send(ctx context.Context, dial DialFunc, network, address string, timeout time.Duration) []byte {
+	con, err := dial(ctx, network, address)
+	if err != nil {
+		return nil
+	}
+	defer con.Close()
+
+	if err := con.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil
+	}
+	if len(p.Payload) > 0 {
+		if _, err := con.Write(p.Payload); err != nil {
+			return nil
+		}
+	}
+
+	buf := make([]byte, responseSize)
+	n, _ := con.Read(buf)
+	return buf[:n]
+}
+
+// Match the response against the matches of a single probe
+func (p *Probe) match(resp []byte) *Service {
+	for _, m := range p.matches {
+		idx := m.pattern.FindSubmatchIndex(resp)
+		if idx == nil {
+			continue
+		}
+		expand := func(template string) string {
+			return string(m.pattern.Expand(nil, []byte(template), resp, idx))
+		}
+		return &Service{
+			Name:    m.service,
+			Product: expand(m.product),
+			Version: expand(m.version),
+			Info:    expand(m.info),
+		}
+	}
+	return nil
+}
+
+// Match the response of a probe. Answers which the probe itself does not
+// know are checked against the matches of the NULL probe as a fallback.
+func (db *DB) Match(probe *Probe, resp []byte) *Service {
+	if s := probe.match(resp); s != nil {
+		return s
+	}
+	for _, p := range db.Probes {
+		if p != probe && len(p.Payload) == 0 {
+			if s := p.match(resp); s != nil {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// Detect the service listening on the port by sending every applicable probe
+// until one of the answers matches
+func (db *DB) Detect(ctx context.Context, dial DialFunc, network, host string, port int, timeout time.Duration) *Service {
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	for _, p := range db.Probes {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !p.applies(port) {
+			continue
+		}
+		resp := p.send(ctx, dial, network, address, timeout)
+		if len(resp) == 0 {
+			continue
+		}
+		if s := db.Match(p, resp); s != nil {
+			return s
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/soner3/net-scan/scan/service"
+)
+
+func probe(t *testing.T, db *service.DB, name string) *service.Probe {
+	for _, p := range db.Probes {
+		if p.Name == name {
+			return p
+		}
+	}
+	t.Fatalf("probe %s not found", name)
+	return nil
+}
+
+func TestMatchEmbedded(t *testing.T) {
+	db, err := service.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		probe    string
+		resp     string
+		expected string
+	}{
+		{"OpenSSH", "NULL", "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3\r\n", "ssh OpenSSH 9.6p1 (protocol 2.0)"},
+		{"GenericSSH", "NULL", "SSH-2.0-Go\r\n", "ssh Go (protocol 2.0)"},
+		{"Postfix", "NULL", "220 mail.example.com ESMTP Postfix\r\n", "smtp Postfix smtpd"},
+		{"MySQL", "NULL", "J\x00\x00\x00\x0a8.0.36\x00\x08\x00\x00\x00", "mysql MySQL 8.0.36"},
+		{"Nginx", "GetRequest", "HTTP/1.1 200 OK\r\nServer: nginx/1.25.3\r\n\r\n", "http nginx 1.25.3"},
+		{"GenericHTTP", "GetRequest", "HTTP/1.0 404 Not Found\r\nServer: Jetty\r\n\r\n", "http Jetty"},
+		{"FallbackToNULL", "GetRequest", "SSH-2.0-OpenSSH_8.0\r\nProtocol mismatch.\r\n", "ssh OpenSSH 8.0 (protocol 2.0)"},
+		{"Redis", "RedisPing", "+PONG\r\n", "redis Redis key-value store"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := db.Match(probe(t, db, tc.probe), []byte(tc.resp))
+			if s == nil {
+				t.Fatalf("Expected %q, got nil instead", tc.expected)
+			}
+			if s.String() != tc.expected {
+				t.Errorf("Expected %q, got %q instead", tc.expected, s.String())
+			}
+		})
+	}
+
+	if s := db.Match(probe(t, db, "NULL"), []byte("garbage")); s != nil {
+		t.Errorf("Expected nil, got %q instead", s)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	testCases := []struct {
+		name        string
+		content     string
+		expectedErr error
+	}{
+		{"Valid", "Probe NULL q||\nmatch ssh m|^SSH-2.0-Internal| p/Internal sshd/\n\nProbe Hello q|HELLO\\r\\n|\nports 7000-7002\nmatch myapp m|^HI v([\\d.]+)|i p/My App/ v/$1/\n", nil},
+		{"UnknownKeyword", "Probe NULL q||\nfoo bar\n", service.ErrSyntax},
+		{"MatchOutsideProbe", "match ssh m|^SSH|\n", service.ErrSyntax},
+		{"InvalidRegex", "Probe NULL q||\nmatch ssh m|^SSH(|\n", service.ErrSyntax},
+		{"UnterminatedPayload", "Probe X q|abc\n", service.ErrSyntax},
+		{"InvalidEscape", "Probe X q|\\q|\n", service.ErrSyntax},
+		{"FileNotFound", "", os.ErrNotExist},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := "not-found.db"
+			if tc.name != "FileNotFound" {
+				f, err := os.CreateTemp(t.TempDir(), "services*.db")
+				if err != nil {
+					t.Fatal(err)
+				}
+				f.WriteString(tc.content)
+				f.Close()
+				filename = f.Name()
+			}
+
+			db, err := service.Load(filename)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected %v, got %v instead", tc.expectedErr, err)
+			}
+			if tc.expectedErr != nil {
+				return
+			}
+
+			s := db.Match(probe(t, db, "NULL"), []byte("SSH-2.0-Internal_1.0\r\n"))
+			if s == nil || s.Product != "Internal sshd" {
+				t.Errorf("Expected file match to take precedence, got %v instead", s)
+			}
+
+			hello := probe(t, db, "Hello")
+			if string(hello.Payload) != "HELLO\r\n" {
+				t.Errorf("Expected %q, got %q instead", "HELLO\r\n", hello.Payload)
+			}
+			if len(hello.Ports) != 3 {
+				t.Errorf("Expected %d, got %d instead", 3, len(hello.Ports))
+			}
+			if s := db.Match(hello, []byte("hi V2.1")); s == nil || s.String() != "myapp My App 2.1" {
+				t.Errorf("Expected %q, got %v instead", "myapp My App 2.1", s)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			con, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			con.Write([]byte("220 ftp.example.com ProFTPD 1.3.8 Server ready.\r\n"))
+			con.Close()
+		}
+	}()
+
+	db, err := service.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := net.Dialer{Timeout: time.Second}
+	s := db.Detect(context.Background(), d.DialContext, "tcp4", "127.0.0.1", ln.Addr().(*net.TCPAddr).Port, time.Second)
+	if s == nil || s.String() != "ftp ProFTPD 1.3.8" {
+		t.Errorf("Expected %q, got %v instead", "ftp ProFTPD 1.3.8", s)
+	}
+}
@@ -0,0 +1,296 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package scan
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Reason of a port which answered the SYN with a RST
+const REASON_RESET = "reset"
+
+var ErrSyn = errors.New("syn scan unavailable")
+
+// Flags of the TCP header
+const (
+	tcpSyn = 0x02
+	tcpRst = 0x04
+	tcpAck = 0x10
+)
+
+// Length of a TCP header without options
+const tcpHeaderLen = 20
+
+// Outstanding probes are identified by the address and port of the target
+type synKey struct {
+	addr netip.Addr
+	port uint16
+}
+
+// A TCP segment received from a target
+type synReply struct {
+	flags byte
+	ack   uint32
+	at    time.Time
+}
+
+// synScanner sends SYN packets on a raw IPv4 socket and hands the replies
+// of its receive loop to the waiting probes. All probes share the source
+// port, a listener reserves it so no other connection of this machine uses
+// it while scanning.
+type synScanner struct {
+	conn    *net.IPConn
+	reserve net.Listener
+	port    uint16
+	source  netip.Addr
+
+	mu      sync.Mutex
+	waiting map[synKey][]chan synReply
+	routes  map[netip.Addr]netip.Addr
+}
+
+// Open the raw socket, bound to the source of the options. Raw sockets
+// need root or CAP_NET_RAW and only Linux delivers TCP segments to them.
+func newSynScanner(opts *Options) (*synScanner, error) {
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("%w: raw TCP sockets are only supported on Linux", ErrSyn)
+	}
+
+	s := &synScanner{waiting: map[synKey][]chan synReply{}, routes: map[netip.Addr]netip.Addr{}}
+	laddr := "0.0.0.0"
+	if src := opts.Source; src != nil && src.IP != nil {
+		ip, ok := netip.AddrFromSlice(src.IP)
+		if !ok || !ip.Unmap().Is4() {
+			return nil, fmt.Errorf("%w: source address %s is no IPv4 address", ErrSyn, src.IP)
+		}
+		s.source = ip.Unmap()
+		laddr = s.source.String()
+	}
+
+	pc, err := opts.Source.ListenConfig().ListenPacket(context.Background(), "ip4:tcp", laddr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w (raw sockets need root or CAP_NET_RAW)", ErrSyn, err)
+	}
+	s.conn = pc.(*net.IPConn)
+
+	if src := opts.Source; src != nil && src.Port != 0 {
+		s.port = uint16(src.Port)
+	} else {
+		s.reserve, err = net.Listen("tcp4", net.JoinHostPort(laddr, "0"))
+		if err != nil {
+			s.conn.Close()
+			return nil, fmt.Errorf("%w: %w", ErrSyn, err)
+		}
+		s.port = uint16(s.reserve.Addr().(*net.TCPAddr).Port)
+	}
+
+	go s.receive()
+	return s, nil
+}
+
+// Stop the receive loop and release the source port
+func (s *synScanner) close() {
+	s.conn.Close()
+	if s.reserve != nil {
+		s.reserve.Close()
+	}
+}
+
+// Read all TCP segments sent to the source port and pass them to the probes
+// waiting for their target until the socket is closed
+func (s *synScanner) receive() {
+	buf := make([]byte, 1500)
+	for {
+		// The IPv4 header is already stripped
+		n, addr, err := s.conn.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil || n < tcpHeaderLen || binary.BigEndian.Uint16(buf[2:]) != s.port {
+			continue
+		}
+		ip, ok := netip.AddrFromSlice(addr.(*net.IPAddr).IP)
+		if !ok {
+			continue
+		}
+
+		key := synKey{addr: ip.Unmap(), port: binary.BigEndian.Uint16(buf[0:])}
+		reply := synReply{flags: buf[13], ack: binary.BigEndian.Uint32(buf[8:]), at: time.Now()}
+		s.mu.Lock()
+		for _, ch := range s.waiting[key] {
+			select {
+			case ch <- reply:
+			default:
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *synScanner) register(key synKey, ch chan synReply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.waiting[key] = append(s.waiting[key], ch)
+}
+
+func (s *synScanner) unregister(key synKey, ch chan synReply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, c := range s.waiting[key] {
+		if c == ch {
+			s.waiting[key] = append(s.waiting[key][:i], s.waiting[key][i+1:]...)
+			break
+		}
+	}
+	if len(s.waiting[key]) == 0 {
+		delete(s.waiting, key)
+	}
+}
+
+// Local address the kernel sends packets to the target from, needed for
+// the checksum. Connecting a UDP socket looks up the route without sending
+// anything.
+func (s *synScanner) localAddr(dst netip.Addr) (netip.Addr, error) {
+	if s.source.IsValid() {
+		return s.source, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if src, ok := s.routes[dst]; ok {
+		return src, nil
+	}
+
+	con, err := net.DialUDP("udp4", nil, net.UDPAddrFromAddrPort(netip.AddrPortFrom(dst, 9)))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer con.Close()
+	src := con.LocalAddr().(*net.UDPAddr).AddrPort().Addr().Unmap()
+	s.routes[dst] = src
+	return src, nil
+}
+
+// Send a SYN to the port and wait up to timeout for the answer. A SYN/ACK
+// marks the port open and is torn down with a RST, a RST marks it closed
+// and silence filtered.
+func (s *synScanner) scan(ctx context.Context, host string, port int, network string, timeout time.Duration, opts *Options) *PortState {
+	ps := NewPortState(port, network)
+	dst, err := netip.ParseAddr(host)
+	if err != nil || !dst.Unmap().Is4() {
+		ps.Open, ps.Reason = ERROR, "syn scan needs an IPv4 address"
+		return ps
+	}
+	dst = dst.Unmap()
+	src, err := s.localAddr(dst)
+	if err != nil {
+		ps.Open, ps.Reason = classify(err)
+		return ps
+	}
+	if err := opts.wait(ctx, host); err != nil {
+		ps.Open, ps.Reason = classify(err)
+		return ps
+	}
+
+	key := synKey{addr: dst, port: uint16(port)}
+	replies := make(chan synReply, 1)
+	s.register(key, replies)
+	defer s.unregister(key, replies)
+
+	seq := rand.Uint32()
+	target := &net.IPAddr{IP: dst.AsSlice()}
+	start := time.Now()
+	if _, err := s.conn.WriteTo(tcpSegment(src, dst, s.port, key.port, seq, tcpSyn), target); err != nil {
+		ps.Open, ps.Reason = classify(err)
+		return ps
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			ps.Open, ps.Reason = classify(ctx.Err())
+			return ps
+		case <-timer.C:
+			ps.Open, ps.Reason = FILTERED, REASON_NO_RESPONSE
+			return ps
+		case r := <-replies:
+			// Late answers to an earlier probe of the same port
+			if r.ack != seq+1 {
+				continue
+			}
+			switch {
+			case r.flags&tcpRst != 0:
+				ps.Open, ps.Reason = CLOSED, REASON_RESET
+			case r.flags&(tcpSyn|tcpAck) == tcpSyn|tcpAck:
+				ps.Open, ps.Reason = OPEN, REASON_CONNECTED
+				s.conn.WriteTo(tcpSegment(src, dst, s.port, key.port, r.ack, tcpRst), target)
+			default:
+				continue
+			}
+			ps.rtt = r.at.Sub(start)
+			return ps
+		}
+	}
+}
+
+// Build a TCP segment without options or payload. The checksum covers the
+// IPv4 pseudo header of RFC 793.
+func tcpSegment(src, dst netip.Addr, srcPort, dstPort uint16, seq uint32, flags byte) []byte {
+	seg := make([]byte, tcpHeaderLen)
+	binary.BigEndian.PutUint16(seg[0:], srcPort)
+	binary.BigEndian.PutUint16(seg[2:], dstPort)
+	binary.BigEndian.PutUint32(seg[4:], seq)
+	seg[12] = tcpHeaderLen / 4 << 4
+	seg[13] = flags
+	binary.BigEndian.PutUint16(seg[14:], 1024)
+
+	pseudo := append(src.AsSlice(), dst.AsSlice()...)
+	pseudo = append(pseudo, 0, 6)
+	pseudo = binary.BigEndian.AppendUint16(pseudo, uint16(len(seg)))
+	binary.BigEndian.PutUint16(seg[16:], checksum(append(pseudo, seg...)))
+	return seg
+}
+
+// Internet checksum of RFC 1071
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package scan_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/soner3/net-scan/host"
+	"github.com/soner3/net-scan/scan"
+)
+
+func TestRunSyn(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	closed, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	hl := host.NewHostList()
+	hl.Add("localhost")
+	opts := scan.NewOptions("tcp4", time.Second, 10, 10)
+	opts.Syn = true
+	res, err := scan.Run(context.Background(), hl.Targets(), []int{ln.Addr().(*net.TCPAddr).Port, closedPort}, opts)
+	if errors.Is(err, scan.ErrSyn) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	states := *res[0].PortStates
+	if states[0].Open != scan.OPEN || states[0].Reason != scan.REASON_CONNECTED {
+		t.Errorf("Expected open (%s), got %s (%s) instead", scan.REASON_CONNECTED, states[0].Open.String(), states[0].Reason)
+	}
+	if states[1].Open != scan.CLOSED || states[1].Reason != scan.REASON_RESET {
+		t.Errorf("Expected closed (%s), got %s (%s) instead", scan.REASON_RESET, states[1].Open.String(), states[1].Reason)
+	}
+
+	// The handshake was never completed, so the listener has nothing to accept
+	ln.(*net.TCPListener).SetDeadline(time.Now().Add(200 * time.Millisecond))
+	if con, err := ln.Accept(); err == nil {
+		con.Close()
+		t.Error("Expected no connection to accept")
+	}
+}
+
+func TestRunSynDiscovery(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	hl := host.NewHostList()
+	hl.Add("127.0.0.1")
+	opts := scan.NewOptions("tcp4", time.Second, 10, 10)
+	opts.Syn = true
+	opts.Discovery = &scan.Discovery{Methods: []string{scan.DISCOVERY_TCP}, Ports: []int{port}, Timeout: time.Second}
+	res, err := scan.Run(context.Background(), hl.Targets(), []int{port}, opts)
+	if errors.Is(err, scan.ErrSyn) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res[0].Down || res[0].Reason != scan.REASON_CONNECTED {
+		t.Errorf("Expected up (%s), got down %v (%s) instead", scan.REASON_CONNECTED, res[0].Down, res[0].Reason)
+	}
+
+	// Neither the discovery nor the probe completed a handshake
+	ln.(*net.TCPListener).SetDeadline(time.Now().Add(200 * time.Millisecond))
+	if con, err := ln.Accept(); err == nil {
+		con.Close()
+		t.Error("Expected no connection to accept")
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package scan
+
+import (
+	"sync"
+	"time"
+)
+
+// Lower bound of adaptive probe timeouts, answers on a LAN arrive within
+// microseconds but a timeout that short would mistake jitter for loss
+const MIN_TIMEOUT = 100 * time.Millisecond
+
+// HostTiming holds the round trip time estimates of a host, measured from
+// the probes which got an answer, and the probe timeout derived from them
+type HostTiming struct {
+	SRTT    time.Duration `json:"srtt_ns"`
+	RTTVar  time.Duration `json:"rttvar_ns"`
+	Timeout time.Duration `json:"timeout_ns"`
+	Samples int           `json:"samples"`
+}
+
+// Estimates the probe timeout of a host like the TCP retransmission timeout
+// (RFC 6298). Until the first answer the maximum timeout is used and every
+// probe without answer doubles the timeout up to the maximum. A fixed
+// estimator always uses the maximum.
+type rttEstimator struct {
+	mu         sync.Mutex
+	adaptive   bool
+	maxTimeout time.Duration
+	timing     HostTiming
+}
+
+func newRTTEstimator(maxTimeout time.Duration, adaptive bool) *rttEstimator {
+	return &rttEstimator{
+		adaptive:   adaptive,
+		maxTimeout: maxTimeout,
+		timing:     HostTiming{Timeout: maxTimeout},
+	}
+}
+
+// Timeout for the next probe
+func (e *rttEstimator) timeout() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.timing.Timeout
+}
+
+// Add the round trip time of an answered probe
+func (e *rttEstimator) sample(rtt time.Duration) {
+	if !e.adaptive {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	t := &e.timing
+	if t.Samples == 0 {
+		t.SRTT = rtt
+		t.RTTVar = rtt / 2
+	} else {
+		t.RTTVar = (3*t.RTTVar + (t.SRTT - rtt).Abs()) / 4
+		t.SRTT = (7*t.SRTT + rtt) / 8
+	}
+	t.Samples++
+	t.Timeout = min(max(t.SRTT+4*t.RTTVar, MIN_TIMEOUT), e.maxTimeout)
+}
+
+// Back the timeout off after a probe without answer
+func (e *rttEstimator) backoff() {
+	if !e.adaptive {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.timing.Timeout = min(2*e.timing.Timeout, e.maxTimeout)
+}
+
+// Current estimates, nil for a fixed estimator
+func (e *rttEstimator) snapshot() *HostTiming {
+	if !e.adaptive {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	timing := e.timing
+	return &timing
+}
@@ -0,0 +1,124 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package scan
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Standard DNS query for the root name servers
+var dnsPayload = []byte{
+	0x13, 0x37, // transaction id
+	0x01, 0x00, // standard query, recursion desired
+	0x00, 0x01, // one question
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00,       // root name
+	0x00, 0x02, // type NS
+	0x00, 0x01, // class IN
+}
+
+// NTPv3 client request
+var ntpPayload = append([]byte{0x1b}, make([]byte, 47)...)
+
+// SNMPv1 get-request for sysDescr.0 with the community "public"
+var snmpPayload = []byte{
+	0x30, 0x29,
+	0x02, 0x01, 0x00, // version 1
+	0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+	0xa0, 0x1c,
+	0x02, 0x04, 0x13, 0x37, 0x13, 0x37, // request id
+	0x02, 0x01, 0x00, // error status
+	0x02, 0x01, 0x00, // error index
+	0x30, 0x0e,
+	0x30, 0x0c,
+	0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00, // 1.3.6.1.2.1.1.1.0
+	0x05, 0x00,
+}
+
+var udpPayloads = map[int][]byte{
+	53:  dnsPayload,
+	123: ntpPayload,
+	161: snmpPayload,
+}
+
+// Payload to send to the given UDP port. Unknown ports get an empty datagram.
+func udpPayload(port int) []byte {
+	if p, ok := udpPayloads[port]; ok {
+		return p
+	}
+	return []byte{}
+}
+
+// Classify an error of a connected UDP socket. A refused read means the
+// target answered with ICMP port unreachable and silence can either mean an
+// open port that ignored the probe or a firewall dropping it.
+func classifyUDP(err error) (state, string) {
+	s, reason := classify(err)
+	switch s {
+	case FILTERED:
+		return OPEN_FILTERED, REASON_NO_RESPONSE
+	case CLOSED:
+		return CLOSED, REASON_PORT_UNREACH
+	}
+	return s, reason
+}
+
+// Scan the UDP port on the given host by sending a protocol specific probe
+// and waiting for either a reply or an ICMP error
+func scanUDP(ctx context.Context, host string, port int, network string, timeout time.Duration, opts *Options) *PortState {
+	ps := NewPortState(port, network)
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	con, err := opts.dial(ctx, network, address)
+	if err != nil {
+		ps.Open, ps.Reason = classify(err)
+		return ps
+	}
+	defer con.Close()
+
+	if err := con.SetDeadline(time.Now().Add(timeout)); err != nil {
+		ps.Open, ps.Reason = classify(err)
+		return ps
+	}
+
+	start := time.Now()
+	if _, err := con.Write(udpPayload(port)); err != nil {
+		ps.Open, ps.Reason = classifyUDP(err)
+		return ps
+	}
+
+	buf := make([]byte, 1500)
+	if _, err := con.Read(buf); err != nil {
+		ps.Open, ps.Reason = classifyUDP(err)
+		if ps.Open == CLOSED {
+			ps.rtt = time.Since(start)
+		}
+		return ps
+	}
+
+	ps.rtt = time.Since(start)
+	ps.Open = OPEN
+	ps.Reason = REASON_UDP_RESPONSE
+	return ps
+}
@@ -0,0 +1,136 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package scan_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/soner3/net-scan/host"
+	"github.com/soner3/net-scan/scan"
+)
+
+func listenUDP(t *testing.T) *net.UDPConn {
+	con, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { con.Close() })
+	return con
+}
+
+func TestRunUDP(t *testing.T) {
+	echo := listenUDP(t)
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := echo.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			echo.WriteToUDP(append([]byte("echo:"), buf[:n]...), addr)
+		}
+	}()
+
+	silent := listenUDP(t)
+
+	closed := listenUDP(t)
+	closedPort := closed.LocalAddr().(*net.UDPAddr).Port
+	closed.Close()
+
+	ports := []int{
+		echo.LocalAddr().(*net.UDPAddr).Port,
+		silent.LocalAddr().(*net.UDPAddr).Port,
+		closedPort,
+	}
+	expected := []string{"open", "open|filtered", "closed"}
+
+	hl := host.NewHostList()
+	hl.Add("127.0.0.1")
+	res, _ := scan.Run(context.Background(), hl.Targets(), ports, scan.NewOptions("udp4", 300*time.Millisecond, 10, 10))
+
+	for i, ps := range *res[0].PortStates {
+		if ps.Open.String() != expected[i] {
+			t.Errorf("Expected %s for port %d, got %s (%s) instead", expected[i], ps.Port, ps.Open.String(), ps.Reason)
+		}
+	}
+}
+
+func TestRunUDPPorts(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	closed := listenUDP(t)
+	closedPort := closed.LocalAddr().(*net.UDPAddr).Port
+	closed.Close()
+
+	hl := host.NewHostList()
+	hl.Add("127.0.0.1")
+	opts := scan.NewOptions("tcp4", 300*time.Millisecond, 10, 10)
+	opts.UDPPorts = []int{closedPort}
+	res, _ := scan.Run(context.Background(), hl.Targets(), []int{ln.Addr().(*net.TCPAddr).Port}, opts)
+
+	// TCP ports come first, followed by the UDP ports
+	states := *res[0].PortStates
+	if len(states) != 2 {
+		t.Fatalf("Expected %d, got %d instead", 2, len(states))
+	}
+	if states[0].Protocol != "tcp4" || states[0].Open != scan.OPEN {
+		t.Errorf("Expected open tcp4 port, got %+v instead", states[0])
+	}
+	if states[1].Protocol != "udp4" || states[1].Port != closedPort || states[1].Open != scan.CLOSED {
+		t.Errorf("Expected closed udp4 port %d, got %+v instead", closedPort, states[1])
+	}
+}
+
+func TestRunRetries(t *testing.T) {
+	silent := listenUDP(t)
+	received := make(chan struct{}, 10)
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			if _, _, err := silent.ReadFromUDP(buf); err != nil {
+				return
+			}
+			received <- struct{}{}
+		}
+	}()
+
+	hl := host.NewHostList()
+	hl.Add("127.0.0.1")
+	opts := scan.NewOptions("udp4", 100*time.Millisecond, 10, 10)
+	opts.Retries = 2
+	res, _ := scan.Run(context.Background(), hl.Targets(), []int{silent.LocalAddr().(*net.UDPAddr).Port}, opts)
+
+	ps := (*res[0].PortStates)[0]
+	if ps.Open != scan.OPEN_FILTERED || ps.Retries != 2 {
+		t.Errorf("Expected open|filtered after 2 retries, got %s after %d instead", ps.Open.String(), ps.Retries)
+	}
+	if len(received) != 3 {
+		t.Errorf("Expected %d probes, got %d instead", 3, len(received))
+	}
+}
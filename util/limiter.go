@@ -0,0 +1,145 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+var ErrLimit = errors.New("invalid rate limit")
+
+// Limiter paces probes. It spaces all probes to stay below a maximum rate,
+// keeps a minimum delay between probes to the same host and adds a random
+// jitter to every delay. A nil *Limiter does not limit anything.
+type Limiter struct {
+	interval  time.Duration
+	hostDelay time.Duration
+	jitter    time.Duration
+
+	mu sync.Mutex
+	// Global slots reserved by waiting and recent probes, sorted
+	slots []time.Time
+	hosts map[string]time.Time
+}
+
+// NewLimiter creates a limiter allowing maxRate probes per second across
+// all hosts. Zero values disable the respective limit, nil is returned if
+// there is nothing to limit.
+func NewLimiter(maxRate float64, hostDelay time.Duration, jitter time.Duration) (*Limiter, error) {
+	if maxRate < 0 {
+		return nil, fmt.Errorf("%w: max-rate must not be negative", ErrLimit)
+	}
+	if hostDelay < 0 {
+		return nil, fmt.Errorf("%w: host-delay must not be negative", ErrLimit)
+	}
+	if jitter < 0 {
+		return nil, fmt.Errorf("%w: jitter must not be negative", ErrLimit)
+	}
+	if maxRate == 0 && hostDelay == 0 && jitter == 0 {
+		return nil, nil
+	}
+
+	l := &Limiter{hostDelay: hostDelay, jitter: jitter, hosts: map[string]time.Time{}}
+	if maxRate > 0 {
+		l.interval = time.Duration(float64(time.Second) / maxRate)
+	}
+	return l, nil
+}
+
+// MinInterval returns the shortest time between two probes of a single
+// host, for probes which send on their own schedule like ping
+func (l *Limiter) MinInterval() time.Duration {
+	if l == nil {
+		return 0
+	}
+	return max(l.interval, l.hostDelay)
+}
+
+// Wait until the next probe to host may be sent. It returns early with the
+// context error if the context is canceled.
+func (l *Limiter) Wait(ctx context.Context, host string) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	slot := now
+	if next, ok := l.hosts[host]; ok && next.After(slot) {
+		slot = next
+	}
+	if l.interval > 0 {
+		slot = l.reserve(now, slot)
+	}
+	if l.hostDelay > 0 {
+		l.hosts[host] = slot.Add(l.hostDelay)
+	}
+	l.mu.Unlock()
+
+	if l.jitter > 0 {
+		slot = slot.Add(rand.N(l.jitter))
+	}
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Reserve the first global slot at or after earliest which keeps the
+// interval to all reserved slots. A host waiting out its host delay only
+// takes the slot it fires at, so other hosts can use the slots before.
+func (l *Limiter) reserve(now time.Time, earliest time.Time) time.Time {
+	// Slots older than an interval can't conflict anymore
+	i := 0
+	for i < len(l.slots) && now.Sub(l.slots[i]) >= l.interval {
+		i++
+	}
+	l.slots = l.slots[i:]
+
+	slot := earliest
+	pos := 0
+	for pos < len(l.slots) {
+		reserved := l.slots[pos]
+		if slot.Sub(reserved) >= l.interval {
+			pos++
+			continue
+		}
+		if reserved.Sub(slot) >= l.interval {
+			break
+		}
+		slot = reserved.Add(l.interval)
+		pos++
+	}
+	l.slots = slices.Insert(l.slots, pos, slot)
+	return slot
+}
@@ -0,0 +1,97 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soner3/net-scan/util"
+)
+
+func TestLimiterHostDelay(t *testing.T) {
+	limiter, err := util.NewLimiter(100, 200*time.Millisecond, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Queue five probes to host A, which need 800ms due to the host delay
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limiter.Wait(context.Background(), "A")
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if err := limiter.Wait(context.Background(), "B"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("Expected host B not to wait behind host A, waited %v", elapsed)
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 800*time.Millisecond {
+		t.Errorf("Expected the host delay to space the probes of host A, done after %v", elapsed)
+	}
+}
+
+func TestLimiterRate(t *testing.T) {
+	limiter, err := util.NewLimiter(50, 100*time.Millisecond, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every probe goes to another host, only the rate spaces them
+	var mu sync.Mutex
+	times := []time.Time{}
+	var wg sync.WaitGroup
+	for _, host := range []string{"A", "B", "C", "D", "E"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limiter.Wait(context.Background(), host)
+			mu.Lock()
+			times = append(times, time.Now())
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	first, last := times[0], times[0]
+	for _, ts := range times {
+		if ts.Before(first) {
+			first = ts
+		}
+		if ts.After(last) {
+			last = ts
+		}
+	}
+	if spread := last.Sub(first); spread < 70*time.Millisecond {
+		t.Errorf("Expected 5 probes at 50/s to take at least 80ms, took %v", spread)
+	}
+}
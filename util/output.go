@@ -0,0 +1,101 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Output formats shared by all commands
+const (
+	OUTPUT_TEXT  = "text"
+	OUTPUT_JSON  = "json"
+	OUTPUT_JSONL = "jsonl"
+	OUTPUT_CSV   = "csv"
+	OUTPUT_TSV   = "tsv"
+
+	OUTPUT_NMAP_XML = "nmap-xml"
+	OUTPUT_JUNIT    = "junit"
+	OUTPUT_SARIF    = "sarif"
+)
+
+var ErrOutput = errors.New("unsupported output format")
+
+// Check that the output format is one of the supported formats
+func ValidateOutput(format string, supported ...string) error {
+	if !slices.Contains(supported, format) {
+		return fmt.Errorf("%w '%s' (supported: %v)", ErrOutput, format, supported)
+	}
+	return nil
+}
+
+// Write the records as one JSON array for json or as one JSON object
+// per line for jsonl
+func WriteJSON[T any](out io.Writer, format string, records []T) error {
+	if format == OUTPUT_JSONL {
+		enc := json.NewEncoder(out)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if records == nil {
+		records = []T{}
+	}
+	return enc.Encode(records)
+}
+
+// Create a writer for csv or tsv and write the header row. Fields
+// containing separators, quotes or line breaks are quoted.
+func NewTableWriter(out io.Writer, format string, header []string) (*csv.Writer, error) {
+	w := csv.NewWriter(out)
+	if format == OUTPUT_TSV {
+		w.Comma = '\t'
+	}
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write a header row followed by the rows, separated by commas for csv and
+// by tabs for tsv
+func WriteTable(out io.Writer, format string, header []string, rows [][]string) error {
+	w, err := NewTableWriter(out, format, header)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	return w.Error()
+}
@@ -0,0 +1,239 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	PROGRESS_AUTO = "auto"
+	PROGRESS_BAR  = "bar"
+	PROGRESS_JSON = "json"
+	PROGRESS_NONE = "none"
+)
+
+// Redraw interval of the status line on a terminal
+const barInterval = 200 * time.Millisecond
+
+var ErrProgress = errors.New("invalid progress mode")
+
+var progressModes = []string{PROGRESS_AUTO, PROGRESS_BAR, PROGRESS_JSON, PROGRESS_NONE}
+
+// Progress reports how many probes of a long-running command are done. It
+// either redraws a single status line or writes a JSON status line every
+// interval. A nil *Progress is valid and reports nothing.
+type Progress struct {
+	out      io.Writer
+	mode     string
+	interval time.Duration
+
+	unit      string
+	foundUnit string
+	total     atomic.Int64
+	done      atomic.Int64
+	found     atomic.Int64
+	start     time.Time
+
+	mu      sync.Mutex
+	drawn   bool
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// Status is a snapshot of the progress, written as JSON status line
+type Status struct {
+	Time      time.Time     `json:"time"`
+	Unit      string        `json:"unit"`
+	Done      int64         `json:"done"`
+	Total     int64         `json:"total"`
+	Found     int64         `json:"found"`
+	FoundUnit string        `json:"found_unit"`
+	Rate      float64       `json:"rate"`
+	Elapsed   time.Duration `json:"elapsed_ns"`
+	ETA       time.Duration `json:"eta_ns"`
+}
+
+// NewProgress creates the progress for the mode. The auto mode draws the
+// status line if out is a terminal and reports nothing otherwise, in which
+// case nil is returned. The interval applies to the JSON status lines.
+func NewProgress(out io.Writer, mode string, interval time.Duration) (*Progress, error) {
+	if !slices.Contains(progressModes, mode) {
+		return nil, fmt.Errorf("%w: '%s' (supported: %v)", ErrProgress, mode, progressModes)
+	}
+	if mode == PROGRESS_JSON && interval <= 0 {
+		return nil, fmt.Errorf("%w: interval must be greater than 0", ErrProgress)
+	}
+
+	if mode == PROGRESS_AUTO {
+		mode = PROGRESS_NONE
+		if f, ok := out.(*os.File); ok {
+			if piped, err := IsPiped(f); err == nil && !piped {
+				mode = PROGRESS_BAR
+			}
+		}
+	}
+	if mode == PROGRESS_NONE {
+		return nil, nil
+	}
+	if mode == PROGRESS_BAR {
+		interval = barInterval
+	}
+
+	return &Progress{out: out, mode: mode, interval: interval}, nil
+}
+
+// Start reporting. The total is counted in unit, found names what the
+// found counter counts, e.g. "open" ports or "alive" hosts.
+func (p *Progress) Start(total int, unit string, found string) {
+	if p == nil {
+		return
+	}
+	p.total.Store(int64(total))
+	p.unit = unit
+	p.foundUnit = found
+	p.start = time.Now()
+	p.stop = make(chan struct{})
+	p.stopped = make(chan struct{})
+
+	go func() {
+		defer close(p.stopped)
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.report()
+			}
+		}
+	}()
+}
+
+// Add finished probes and found results
+func (p *Progress) Add(done int, found int) {
+	if p == nil {
+		return
+	}
+	p.done.Add(int64(done))
+	p.found.Add(int64(found))
+}
+
+// Stop reporting and write the final status
+func (p *Progress) Stop() {
+	if p == nil || p.stop == nil {
+		return
+	}
+	close(p.stop)
+	<-p.stopped
+	p.report()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.mode == PROGRESS_BAR {
+		fmt.Fprintln(p.out)
+		p.drawn = false
+	}
+}
+
+// AddTotal corrects the total once it turns out a host needs more or fewer
+// probes than expected
+func (p *Progress) AddTotal(n int) {
+	if p == nil {
+		return
+	}
+	p.total.Add(int64(n))
+}
+
+// Status returns the current snapshot
+func (p *Progress) Status() Status {
+	now := time.Now()
+	st := Status{
+		Time:      now,
+		Unit:      p.unit,
+		Done:      p.done.Load(),
+		Total:     p.total.Load(),
+		Found:     p.found.Load(),
+		FoundUnit: p.foundUnit,
+		Elapsed:   now.Sub(p.start),
+	}
+	if secs := st.Elapsed.Seconds(); secs > 0 {
+		st.Rate = float64(st.Done) / secs
+	}
+	if st.Rate > 0 && st.Total > st.Done {
+		st.ETA = time.Duration(float64(st.Total-st.Done) / st.Rate * float64(time.Second))
+	}
+	return st
+}
+
+// Write the current status as status line or as JSON line
+func (p *Progress) report() {
+	st := p.Status()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.mode == PROGRESS_JSON {
+		json.NewEncoder(p.out).Encode(st)
+		return
+	}
+
+	percent := 100.0
+	if st.Total > 0 {
+		percent = float64(st.Done) / float64(st.Total) * 100
+	}
+	fmt.Fprintf(p.out, "\r\033[K%s %d/%d (%.1f%%) | %s %d | %.0f/s | ETA %s",
+		st.Unit, st.Done, st.Total, percent, p.foundUnit, st.Found, st.Rate, st.ETA.Round(time.Second))
+	p.drawn = true
+}
+
+// Writer wraps w so the status line is cleared before anything else is
+// written to the terminal. It is redrawn with the next update.
+func (p *Progress) Writer(w io.Writer) io.Writer {
+	if p == nil || p.mode != PROGRESS_BAR {
+		return w
+	}
+	return &progressWriter{p: p, w: w}
+}
+
+type progressWriter struct {
+	p *Progress
+	w io.Writer
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	pw.p.mu.Lock()
+	defer pw.p.mu.Unlock()
+	if pw.p.drawn {
+		fmt.Fprint(pw.p.out, "\r\033[K")
+		pw.p.drawn = false
+	}
+	return pw.w.Write(b)
+}
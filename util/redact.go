@@ -0,0 +1,66 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import "regexp"
+
+// Credentials in URLs like the one of --proxy and in config keys named like
+// credentials are masked before a command line or configuration is written
+// anywhere
+var (
+	userinfo      = regexp.MustCompile(`://[^/?#@\s]*@`)
+	credentialKey = regexp.MustCompile(`(?i)password|passwd|secret|token|credential`)
+)
+
+const REDACTED = "redacted"
+
+// Redact masks the credentials of a string, a list or a map of settings
+// and returns a copy. Other values are returned as they are.
+func Redact(value any) any {
+	switch v := value.(type) {
+	case string:
+		return userinfo.ReplaceAllString(v, "://"+REDACTED+"@")
+	case []string:
+		redacted := make([]string, len(v))
+		for i, s := range v {
+			redacted[i] = Redact(s).(string)
+		}
+		return redacted
+	case []any:
+		redacted := make([]any, len(v))
+		for i, item := range v {
+			redacted[i] = Redact(item)
+		}
+		return redacted
+	case map[string]any:
+		redacted := make(map[string]any, len(v))
+		for key, item := range v {
+			if credentialKey.MatchString(key) {
+				redacted[key] = REDACTED
+			} else {
+				redacted[key] = Redact(item)
+			}
+		}
+		return redacted
+	}
+	return value
+}
@@ -0,0 +1,136 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"encoding/json"
+	"io"
+)
+
+const (
+	SARIF_VERSION = "2.1.0"
+	SARIF_SCHEMA  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	// Levels of SARIF results
+	SARIF_ERROR   = "error"
+	SARIF_WARNING = "warning"
+	SARIF_NOTE    = "note"
+)
+
+// SarifLog is a SARIF report of findings, which CI systems show as code
+// scanning alerts. net-scan writes a single run per report.
+type SarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SarifRun `json:"runs"`
+}
+
+type SarifRun struct {
+	Tool    SarifTool     `json:"tool"`
+	Results []SarifResult `json:"results"`
+}
+
+type SarifTool struct {
+	Driver SarifDriver `json:"driver"`
+}
+
+type SarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []SarifRule `json:"rules"`
+}
+
+// SarifRule describes a kind of finding
+type SarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     SarifMessage       `json:"shortDescription"`
+	DefaultConfiguration SarifConfiguration `json:"defaultConfiguration"`
+}
+
+type SarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type SarifMessage struct {
+	Text string `json:"text"`
+}
+
+// SarifResult is a single finding. Network targets have no files, so they
+// are located by logical locations like "10.0.0.1:22/tcp".
+type SarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    SarifMessage    `json:"message"`
+	Locations  []SarifLocation `json:"locations"`
+	Properties map[string]any  `json:"properties,omitempty"`
+}
+
+type SarifLocation struct {
+	LogicalLocations []SarifLogicalLocation `json:"logicalLocations"`
+}
+
+type SarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// Create a report of net-scan with the rules of its findings
+func NewSarifLog(rules ...SarifRule) *SarifLog {
+	return &SarifLog{
+		Schema:  SARIF_SCHEMA,
+		Version: SARIF_VERSION,
+		Runs: []SarifRun{{
+			Tool: SarifTool{Driver: SarifDriver{
+				Name:           "net-scan",
+				Version:        VERSION,
+				InformationURI: "https://github.com/soner3/net-scan",
+				Rules:          rules,
+			}},
+			Results: []SarifResult{},
+		}},
+	}
+}
+
+// Add a finding of the rule located at a network resource like a host or
+// a port of a host
+func (log *SarifLog) Add(rule SarifRule, resource string, message string, properties map[string]any) {
+	run := &log.Runs[0]
+	run.Results = append(run.Results, SarifResult{
+		RuleID:  rule.ID,
+		Level:   rule.DefaultConfiguration.Level,
+		Message: SarifMessage{Text: message},
+		Locations: []SarifLocation{{
+			LogicalLocations: []SarifLogicalLocation{{Name: resource, FullyQualifiedName: resource, Kind: "resource"}},
+		}},
+		Properties: properties,
+	})
+}
+
+// Write the report as indented JSON
+func WriteSarif(out io.Writer, log *SarifLog) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
@@ -0,0 +1,142 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var ErrSource = errors.New("invalid source")
+
+// Source binds outgoing connections to a local address, interface and port,
+// e.g. to test firewall rules from a specific interface of a multi-homed
+// host. A nil *Source leaves the choice to the routing table.
+type Source struct {
+	IP    net.IP
+	Iface string
+	Port  int
+}
+
+// NewSource checks the source address, interface and port. Empty values
+// are chosen by the system, nil is returned if there is nothing to bind.
+func NewSource(ip string, iface string, port int) (*Source, error) {
+	if ip == "" && iface == "" && port == 0 {
+		return nil, nil
+	}
+
+	src := &Source{Iface: iface, Port: port}
+	if ip != "" {
+		if src.IP = net.ParseIP(ip); src.IP == nil {
+			return nil, fmt.Errorf("%w: '%s' is not an IP address", ErrSource, ip)
+		}
+	}
+	if iface != "" {
+		if _, err := net.InterfaceByName(iface); err != nil {
+			return nil, fmt.Errorf("%w: interface '%s': %w", ErrSource, iface, err)
+		}
+		if !canBindToDevice {
+			return nil, fmt.Errorf("%w: binding to an interface is only supported on Linux", ErrSource)
+		}
+	}
+	if port < 0 || port > 65535 {
+		return nil, fmt.Errorf("%w: port %d out of range", ErrSource, port)
+	}
+	return src, nil
+}
+
+// Check whether the source address can reach a network of the given
+// address family, e.g. an IPv4 source can't dial tcp6
+func (src *Source) Supports(network string) bool {
+	if src == nil || src.IP == nil {
+		return true
+	}
+	switch {
+	case strings.HasSuffix(network, "4"):
+		return src.IP.To4() != nil
+	case strings.HasSuffix(network, "6"):
+		return src.IP.To4() == nil
+	}
+	return true
+}
+
+// Dialer returns a dialer for the network which binds every connection to
+// the source. A fixed port is shared by all connections, on Linux the
+// sockets reuse it to probe several destinations at once.
+func (src *Source) Dialer(network string, timeout time.Duration) *net.Dialer {
+	d := &net.Dialer{Timeout: timeout}
+	if src == nil {
+		return d
+	}
+
+	if src.IP != nil || src.Port != 0 {
+		switch {
+		case strings.HasPrefix(network, "tcp"):
+			d.LocalAddr = &net.TCPAddr{IP: src.IP, Port: src.Port}
+		case strings.HasPrefix(network, "udp"):
+			d.LocalAddr = &net.UDPAddr{IP: src.IP, Port: src.Port}
+		case strings.HasPrefix(network, "ip"):
+			d.LocalAddr = &net.IPAddr{IP: src.IP}
+		}
+	}
+
+	d.Control = src.rawControl
+	return d
+}
+
+// Ephemeral returns the source without its fixed port, the system picks
+// one per connection. Connections to a host and port which a probe already
+// used need it: a fixed port can't connect to the same address twice while
+// the first connection is open or in TIME_WAIT.
+func (src *Source) Ephemeral() *Source {
+	if src == nil || src.Port == 0 {
+		return src
+	}
+	if src.IP == nil && src.Iface == "" {
+		return nil
+	}
+	eph := *src
+	eph.Port = 0
+	return &eph
+}
+
+// ListenConfig returns a listen config which binds sockets to the
+// interface of the source, e.g. the raw sockets of a SYN scan. The address
+// to listen on is left to the caller.
+func (src *Source) ListenConfig() *net.ListenConfig {
+	if src == nil {
+		return &net.ListenConfig{}
+	}
+	return &net.ListenConfig{Control: src.rawControl}
+}
+
+func (src *Source) rawControl(network, address string, c syscall.RawConn) error {
+	var err error
+	if ctrlErr := c.Control(func(fd uintptr) { err = src.control(fd) }); ctrlErr != nil {
+		return ctrlErr
+	}
+	return err
+}
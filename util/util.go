@@ -25,9 +25,11 @@ import (
 	"os"
 )
 
-// IsPiped returns true if input is piped or redirected (not terminal).
-func IsPiped() (bool, error) {
-	info, err := os.Stdin.Stat()
+const VERSION = "0.1"
+
+// IsPiped returns true if the file is piped or redirected (not terminal).
+func IsPiped(f *os.File) (bool, error) {
+	info, err := f.Stat()
 	if err != nil {
 		return false, err
 	}
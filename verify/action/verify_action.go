@@ -0,0 +1,236 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+
+	"github.com/soner3/net-scan/scan"
+	"github.com/soner3/net-scan/util"
+	"github.com/soner3/net-scan/verify"
+)
+
+var (
+	ErrValue = errors.New("invalid value")
+	// ErrViolation is returned if a check failed, so scripts can gate on
+	// the exit code
+	ErrViolation = errors.New("policy violated")
+)
+
+var outputs = []string{util.OUTPUT_TEXT, util.OUTPUT_JSON, util.OUTPUT_JUNIT, util.OUTPUT_SARIF}
+
+// Failed checks are the findings of a verification
+var violationRule = util.SarifRule{
+	ID:                   "policy-violation",
+	Name:                 "PolicyViolation",
+	ShortDescription:     util.SarifMessage{Text: "A port is not in the state the policy expects"},
+	DefaultConfiguration: util.SarifConfiguration{Level: util.SARIF_ERROR},
+}
+
+var networks = []string{"tcp", "tcp4", "tcp6"}
+
+type Config struct {
+	Policy string
+	Output string
+	Opts   *scan.Options
+
+	// Progress reports finished probes while scanning, nil disables it
+	Progress *util.Progress
+}
+
+func NewConfig(policy string, output string, opts *scan.Options) *Config {
+	return &Config{
+		Policy: policy,
+		Output: output,
+		Opts:   opts,
+	}
+}
+
+func (cfg *Config) validate() error {
+	if cfg.Policy == "" {
+		return fmt.Errorf("%w: policy must be set", ErrValue)
+	}
+	if err := util.ValidateOutput(cfg.Output, outputs...); err != nil {
+		return err
+	}
+	if !slices.Contains(networks, cfg.Opts.Network) {
+		return fmt.Errorf("%w: unsupported network '%s' (supported: %v)", ErrValue, cfg.Opts.Network, networks)
+	}
+	if cfg.Opts.Timeout <= 0 {
+		return fmt.Errorf("%w: timeout must be greater than 0", ErrValue)
+	}
+	if cfg.Opts.Retries < 0 {
+		return fmt.Errorf("%w: retries must not be negative", ErrValue)
+	}
+	if cfg.Opts.Concurrency < 1 {
+		return fmt.Errorf("%w: concurrency must be greater than 0", ErrValue)
+	}
+	if cfg.Opts.HostConcurrency < 1 {
+		return fmt.Errorf("%w: host-concurrency must be greater than 0", ErrValue)
+	}
+	return nil
+}
+
+// The JSON output of a verification
+type report struct {
+	Passed bool           `json:"passed"`
+	Total  int            `json:"total"`
+	Failed int            `json:"failed"`
+	Checks []verify.Check `json:"checks"`
+}
+
+// Describe what a failed check found
+func violation(c *verify.Check) string {
+	if c.Expected == verify.EXPECT_FOUND {
+		return "host not found"
+	}
+	return fmt.Sprintf("%d/%s is %s, expected %s", c.Port, c.Protocol, c.State, c.Expected)
+}
+
+// Print a line per rule followed by its violations
+func writeText(out io.Writer, policy *verify.Policy, checks []verify.Check) error {
+	output := ""
+	for _, rule := range policy.Rules {
+		ruleChecks := []verify.Check{}
+		for _, c := range checks {
+			if c.Rule == rule.Name {
+				ruleChecks = append(ruleChecks, c)
+			}
+		}
+		failed := verify.Failed(ruleChecks)
+		if len(failed) == 0 {
+			output += fmt.Sprintf("%s: PASS (%d checks)\n", rule.Name, len(ruleChecks))
+			continue
+		}
+		output += fmt.Sprintf("%s: FAIL (%d of %d checks failed)\n", rule.Name, len(failed), len(ruleChecks))
+		for _, c := range failed {
+			output += fmt.Sprintf("\t%s: %s\n", c.Host, violation(&c))
+		}
+	}
+
+	failed := len(verify.Failed(checks))
+	if failed > 0 {
+		output += fmt.Sprintf("\nPolicy violated: %d of %d checks failed\n", failed, len(checks))
+	} else {
+		output += fmt.Sprintf("\nPolicy passed: %d checks\n", len(checks))
+	}
+	_, err := fmt.Fprint(out, output)
+	return err
+}
+
+// One test suite per rule and one test case per check, named after the
+// host and the expected port state
+func writeJUnit(out io.Writer, policy *verify.Policy, checks []verify.Check) error {
+	junit := &util.JUnitReport{Name: "net-scan verify"}
+	for _, rule := range policy.Rules {
+		suite := util.JUnitSuite{Name: rule.Name, Cases: []util.JUnitCase{}}
+		for _, c := range checks {
+			if c.Rule != rule.Name {
+				continue
+			}
+			tc := util.JUnitCase{Name: "host found", ClassName: c.Host}
+			if c.Expected != verify.EXPECT_FOUND {
+				tc.Name = fmt.Sprintf("%d/%s %s", c.Port, c.Protocol, c.Expected)
+			}
+			if !c.Passed {
+				tc.Failure = &util.JUnitFailure{Message: violation(&c), Type: c.State, Text: c.Reason}
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		junit.Suites = append(junit.Suites, suite)
+	}
+	return util.WriteJUnit(out, junit)
+}
+
+// One finding per failed check, located at the host and port
+func writeSarif(out io.Writer, checks []verify.Check) error {
+	log := util.NewSarifLog(violationRule)
+	for _, c := range verify.Failed(checks) {
+		resource := c.Host
+		if c.Expected != verify.EXPECT_FOUND {
+			resource = net.JoinHostPort(c.Host, strconv.Itoa(c.Port)) + "/" + c.Protocol
+		}
+		properties := map[string]any{"rule": c.Rule, "host": c.Host, "expected": c.Expected, "state": c.State}
+		if c.Port != 0 {
+			properties["port"], properties["protocol"] = c.Port, c.Protocol
+		}
+		log.Add(violationRule, resource, fmt.Sprintf("%s: %s: %s", c.Rule, c.Host, violation(&c)), properties)
+	}
+	return util.WriteSarif(out, log)
+}
+
+// Scan the hosts of the policy and report the checks. Returns ErrViolation
+// once the report is written if any check failed.
+func VerifyAction(ctx context.Context, out io.Writer, cfg *Config) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	policy, err := verify.Load(cfg.Policy)
+	if err != nil {
+		return err
+	}
+
+	opts := *cfg.Opts
+	opts.OnPortState = func(host string, ps *scan.PortState) {
+		if ps.Open == scan.OPEN {
+			cfg.Progress.Add(1, 1)
+		} else {
+			cfg.Progress.Add(1, 0)
+		}
+	}
+	out = cfg.Progress.Writer(out)
+	cfg.Progress.Start(policy.Probes(), "probes", "open")
+	checks, err := verify.Run(ctx, policy, &opts)
+	cfg.Progress.Stop()
+	if err != nil {
+		return err
+	}
+
+	failed := len(verify.Failed(checks))
+	switch cfg.Output {
+	case util.OUTPUT_JSON:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report{Passed: failed == 0, Total: len(checks), Failed: failed, Checks: checks})
+	case util.OUTPUT_JUNIT:
+		err = writeJUnit(out, policy, checks)
+	case util.OUTPUT_SARIF:
+		err = writeSarif(out, checks)
+	default:
+		err = writeText(out, policy, checks)
+	}
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d checks failed", ErrViolation, failed, len(checks))
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package action_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soner3/net-scan/scan"
+	"github.com/soner3/net-scan/util"
+	"github.com/soner3/net-scan/verify/action"
+)
+
+// Write a policy which expects the port to be open and returns it
+// together with the listening port
+func setup(t *testing.T, closed int) (string, int) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	policy := fmt.Sprintf("rules:\n  - name: local\n    hosts: [127.0.0.1]\n    open: [%d]\n", port)
+	if closed != 0 {
+		policy += fmt.Sprintf("    closed: [%d]\n", closed)
+	}
+	filename := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(filename, []byte(policy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filename, port
+}
+
+func TestVerifyActionValidation(t *testing.T) {
+	testData := []struct {
+		name        string
+		policy      string
+		output      string
+		opts        *scan.Options
+		expectedErr error
+	}{
+		{"NoPolicy", "", util.OUTPUT_TEXT, scan.NewOptions("tcp", time.Second, 1, 1), action.ErrValue},
+		{"Output", "policy.yaml", util.OUTPUT_CSV, scan.NewOptions("tcp", time.Second, 1, 1), util.ErrOutput},
+		{"Network", "policy.yaml", util.OUTPUT_TEXT, scan.NewOptions("udp", time.Second, 1, 1), action.ErrValue},
+		{"Timeout", "policy.yaml", util.OUTPUT_TEXT, scan.NewOptions("tcp", 0, 1, 1), action.ErrValue},
+		{"Concurrency", "policy.yaml", util.OUTPUT_TEXT, scan.NewOptions("tcp", time.Second, 0, 1), action.ErrValue},
+		{"MissingFile", "missing.yaml", util.OUTPUT_TEXT, scan.NewOptions("tcp", time.Second, 1, 1), os.ErrNotExist},
+	}
+
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			err := action.VerifyAction(context.Background(), &bytes.Buffer{}, action.NewConfig(td.policy, td.output, td.opts))
+			if !errors.Is(err, td.expectedErr) {
+				t.Errorf("Expected %q, got %q instead", td.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifyAction(t *testing.T) {
+	policy, port := setup(t, 0)
+	var out bytes.Buffer
+	cfg := action.NewConfig(policy, util.OUTPUT_TEXT, scan.NewOptions("tcp", time.Second, 10, 10))
+	if err := action.VerifyAction(context.Background(), &out, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "local: PASS (1 checks)\n\nPolicy passed: 1 checks\n"; out.String() != expected {
+		t.Errorf("Expected %q, got %q instead", expected, out.String())
+	}
+
+	// The open port violates a second policy which expects it closed
+	violated, _ := setup(t, port)
+	out.Reset()
+	err := action.VerifyAction(context.Background(), &out, action.NewConfig(violated, util.OUTPUT_JSON, scan.NewOptions("tcp", time.Second, 10, 10)))
+	if !errors.Is(err, action.ErrViolation) {
+		t.Errorf("Expected %q, got %q instead", action.ErrViolation, err)
+	}
+	var report struct {
+		Passed bool `json:"passed"`
+		Total  int  `json:"total"`
+		Failed int  `json:"failed"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Passed || report.Total != 2 || report.Failed != 1 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+func TestVerifyActionJUnit(t *testing.T) {
+	// The port of the first policy is open and must be closed in the second
+	_, port := setup(t, 0)
+	policy, _ := setup(t, port)
+
+	var out bytes.Buffer
+	err := action.VerifyAction(context.Background(), &out, action.NewConfig(policy, util.OUTPUT_JUNIT, scan.NewOptions("tcp", time.Second, 10, 10)))
+	if !errors.Is(err, action.ErrViolation) {
+		t.Errorf("Expected %q, got %q instead", action.ErrViolation, err)
+	}
+
+	var report util.JUnitReport
+	if err := xml.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Tests != 2 || report.Failures != 1 || len(report.Suites) != 1 || report.Suites[0].Name != "local" {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	for _, tc := range report.Suites[0].Cases {
+		if tc.Name != fmt.Sprintf("%d/tcp closed", port) {
+			continue
+		}
+		if tc.Failure == nil || !strings.HasSuffix(tc.Failure.Message, "is open, expected closed") {
+			t.Errorf("Expected the closed port to fail, got %+v instead", tc)
+		}
+		return
+	}
+	t.Errorf("Expected a case for port %d, got %+v instead", port, report.Suites[0].Cases)
+}
+
+func TestVerifyActionSarif(t *testing.T) {
+	_, port := setup(t, 0)
+	policy, _ := setup(t, port)
+
+	var out bytes.Buffer
+	err := action.VerifyAction(context.Background(), &out, action.NewConfig(policy, util.OUTPUT_SARIF, scan.NewOptions("tcp", time.Second, 10, 10)))
+	if !errors.Is(err, action.ErrViolation) {
+		t.Errorf("Expected %q, got %q instead", action.ErrViolation, err)
+	}
+
+	var log util.SarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	findings := log.Runs[0].Results
+	if len(findings) != 1 || findings[0].Level != util.SARIF_ERROR {
+		t.Fatalf("Expected one error, got %+v instead", findings)
+	}
+	if location := findings[0].Locations[0].LogicalLocations[0].Name; location != fmt.Sprintf("127.0.0.1:%d/tcp", port) {
+		t.Errorf("Expected the closed port as location, got %s instead", location)
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/soner3/net-scan/host"
+	"github.com/soner3/net-scan/scan"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrPolicy = errors.New("invalid policy")
+
+// Ports is a port expression like the one of scan --ports. In the policy
+// file it is either a string or a list of ports and items.
+type Ports string
+
+func (p *Ports) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		items := []string{}
+		if err := node.Decode(&items); err != nil {
+			return err
+		}
+		*p = Ports(strings.Join(items, ","))
+		return nil
+	}
+	var expr string
+	if err := node.Decode(&expr); err != nil {
+		return err
+	}
+	*p = Ports(expr)
+	return nil
+}
+
+// Rule states which ports of its hosts must be open and which must be
+// closed or filtered. Hosts are names of groups or host file entries.
+type Rule struct {
+	Name   string   `yaml:"name"`
+	Hosts  []string `yaml:"hosts"`
+	Open   Ports    `yaml:"open"`
+	Closed Ports    `yaml:"closed"`
+
+	open   *scan.PortSpec
+	closed *scan.PortSpec
+}
+
+// Policy is the expected state of the ports of a network
+type Policy struct {
+	// Groups name lists of host file entries (hosts, CIDRs and ranges)
+	Groups map[string][]string `yaml:"groups"`
+	Rules  []Rule              `yaml:"rules"`
+}
+
+// Load and validate a policy file
+func Load(filename string) (*Policy, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	policy := &Policy{}
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(policy); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPolicy, err.Error())
+	}
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (p *Policy) validate() error {
+	for name, entries := range p.Groups {
+		for _, entry := range entries {
+			if _, err := host.ParseTarget(entry); err != nil {
+				return fmt.Errorf("%w: group %s: %s", ErrPolicy, name, err.Error())
+			}
+		}
+	}
+
+	if len(p.Rules) == 0 {
+		return fmt.Errorf("%w: no rules", ErrPolicy)
+	}
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if err := p.validateRule(rule); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrPolicy, rule.Name, err)
+		}
+	}
+	return nil
+}
+
+func (p *Policy) validateRule(rule *Rule) error {
+	if len(rule.Hosts) == 0 {
+		return errors.New("no hosts")
+	}
+	for _, h := range rule.Hosts {
+		if _, ok := p.Groups[h]; ok {
+			continue
+		}
+		if _, err := host.ParseTarget(h); err != nil {
+			return err
+		}
+	}
+
+	if rule.Open == "" && rule.Closed == "" {
+		return errors.New("neither open nor closed ports")
+	}
+	var err error
+	if rule.open, err = scan.ParsePorts(string(rule.Open), scan.PROTO_TCP); err != nil {
+		return err
+	}
+	if rule.closed, err = scan.ParsePorts(string(rule.Closed), scan.PROTO_TCP); err != nil {
+		return err
+	}
+	for _, protocol := range []string{scan.PROTO_TCP, scan.PROTO_UDP} {
+		for _, port := range rule.open.Ports(protocol) {
+			if slices.Contains(rule.closed.Ports(protocol), port) {
+				return fmt.Errorf("port %d/%s must be both open and closed", port, protocol)
+			}
+		}
+	}
+	return nil
+}
+
+// Targets returns an iterator over the hosts of the rule with its groups,
+// CIDR blocks and ranges expanded. Hosts of several entries are only
+// included once.
+func (p *Policy) Targets(rule *Rule) iter.Seq[string] {
+	hl := host.NewHostList()
+	for _, h := range rule.Hosts {
+		entries, ok := p.Groups[h]
+		if !ok {
+			entries = []string{h}
+		}
+		for _, entry := range entries {
+			// The entries are validated, only duplicates fail
+			hl.Add(entry)
+		}
+	}
+
+	return func(yield func(string) bool) {
+		seen := map[string]bool{}
+		for target := range hl.Targets() {
+			if seen[target] {
+				continue
+			}
+			seen[target] = true
+			if !yield(target) {
+				return
+			}
+		}
+	}
+}
+
+// Probes returns the number of ports Run probes for all rules
+func (p *Policy) Probes() int {
+	probes := 0
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		ports := len(rule.open.TCP) + len(rule.open.UDP) + len(rule.closed.TCP) + len(rule.closed.UDP)
+		for range p.Targets(rule) {
+			probes += ports
+		}
+	}
+	return probes
+}
@@ -0,0 +1,124 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package verify
+
+import (
+	"context"
+	"slices"
+
+	"github.com/soner3/net-scan/scan"
+)
+
+// Expected states of a check. Closed ports may also be filtered, all
+// other states fail them.
+const (
+	EXPECT_OPEN   = "open"
+	EXPECT_CLOSED = "closed"
+	EXPECT_FOUND  = "found"
+)
+
+// Check is the outcome of one expected state of a host and port. Hosts
+// which were not found get a single failed check without a port.
+type Check struct {
+	Rule     string `json:"rule"`
+	Host     string `json:"host"`
+	Port     int    `json:"port,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+	Expected string `json:"expected"`
+	State    string `json:"state"`
+	Reason   string `json:"reason,omitempty"`
+	Passed   bool   `json:"passed"`
+}
+
+// Compare the scanned ports of a host with the expectations of the rule
+func (rule *Rule) check(res *scan.ScanResult) []Check {
+	if res.NotFound {
+		return []Check{{Rule: rule.Name, Host: res.Host, Expected: EXPECT_FOUND, State: "not-found"}}
+	}
+
+	checks := []Check{}
+	for _, ps := range *res.PortStates {
+		check := Check{
+			Rule:     rule.Name,
+			Host:     res.Host,
+			Port:     ps.Port,
+			Protocol: ps.Protocol,
+			State:    ps.Open.String(),
+			Reason:   ps.Reason,
+		}
+		// The protocol of a port state is its network, e.g. udp4
+		if slices.Contains(rule.open.Ports(scan.Protocol(ps.Protocol)), ps.Port) {
+			check.Expected = EXPECT_OPEN
+			check.Passed = ps.Open == scan.OPEN
+		} else {
+			// Failed probes and unreachable hosts prove nothing, neither
+			// does open|filtered
+			check.Expected = EXPECT_CLOSED
+			check.Passed = ps.Open == scan.CLOSED || ps.Open == scan.FILTERED
+		}
+		if !check.Passed && check.Reason == "" {
+			check.Reason = check.State
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// Run scans the hosts of every rule for its open and closed ports and
+// returns the checks in the order of the rules, hosts and ports. The
+// network of the options must be tcp, tcp4 or tcp6, UDP ports of the
+// rules are probed over the matching udp network. Once the context is
+// canceled the checks so far are returned together with the context error.
+func Run(ctx context.Context, policy *Policy, opts *scan.Options) ([]Check, error) {
+	checks := []Check{}
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+		ports := slices.Concat(rule.open.TCP, rule.closed.TCP)
+		slices.Sort(ports)
+
+		ruleOpts := *opts
+		ruleOpts.UDPPorts = slices.Concat(rule.open.UDP, rule.closed.UDP)
+		slices.Sort(ruleOpts.UDPPorts)
+		ruleOpts.OnResult = func(res *scan.ScanResult) {
+			checks = append(checks, rule.check(res)...)
+			if opts.OnResult != nil {
+				opts.OnResult(res)
+			}
+		}
+
+		if err := scan.Stream(ctx, policy.Targets(rule), ports, &ruleOpts); err != nil {
+			return checks, err
+		}
+	}
+	return checks, ctx.Err()
+}
+
+// Failed returns the checks which did not pass
+func Failed(checks []Check) []Check {
+	failed := []Check{}
+	for _, c := range checks {
+		if !c.Passed {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,85 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package verify
+
+import (
+	"testing"
+
+	"github.com/soner3/net-scan/scan"
+)
+
+func TestCheck(t *testing.T) {
+	open, err := scan.ParsePorts("22,U:53", scan.PROTO_TCP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed, err := scan.ParsePorts("80,U:161", scan.PROTO_TCP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := &Rule{Name: "rule", open: open, closed: closed}
+
+	testData := []struct {
+		name           string
+		port           int
+		protocol       string
+		state          string
+		reason         string
+		expectedPassed bool
+		expectedReason string
+	}{
+		{"OpenPassed", 22, "tcp", "open", scan.REASON_CONNECTED, true, scan.REASON_CONNECTED},
+		{"OpenFailed", 22, "tcp", "filtered", scan.REASON_NO_RESPONSE, false, scan.REASON_NO_RESPONSE},
+		{"Closed", 80, "tcp", "closed", scan.REASON_REFUSED, true, scan.REASON_REFUSED},
+		{"Filtered", 80, "tcp", "filtered", scan.REASON_NO_RESPONSE, true, scan.REASON_NO_RESPONSE},
+		{"ClosedOpen", 80, "tcp", "open", scan.REASON_CONNECTED, false, scan.REASON_CONNECTED},
+		{"ClosedError", 80, "tcp", "error", scan.REASON_TOO_MANY_FILES, false, scan.REASON_TOO_MANY_FILES},
+		{"ClosedErrorNoReason", 80, "tcp", "error", "", false, "error"},
+		{"ClosedUnreachable", 80, "tcp", "unreachable", scan.REASON_HOST_UNREACH, false, scan.REASON_HOST_UNREACH},
+		{"ClosedOpenFiltered", 161, "udp", "open|filtered", scan.REASON_NO_RESPONSE, false, scan.REASON_NO_RESPONSE},
+		// Port states of tcp4 and tcp6 scans carry the network of the family
+		{"OpenTCP4", 22, "tcp4", "open", scan.REASON_CONNECTED, true, scan.REASON_CONNECTED},
+		{"OpenUDP4", 53, "udp4", "open", scan.REASON_UDP_RESPONSE, true, scan.REASON_UDP_RESPONSE},
+		{"OpenUDP6Failed", 53, "udp6", "closed", scan.REASON_PORT_UNREACH, false, scan.REASON_PORT_UNREACH},
+		{"ClosedUDP4", 161, "udp4", "closed", scan.REASON_PORT_UNREACH, true, scan.REASON_PORT_UNREACH},
+	}
+
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			ps := scan.NewPortState(td.port, td.protocol)
+			if err := ps.Open.UnmarshalText([]byte(td.state)); err != nil {
+				t.Fatal(err)
+			}
+			ps.Reason = td.reason
+			res := scan.NewScanResult("host")
+			res.PortStates = &[]scan.PortState{*ps}
+
+			checks := rule.check(res)
+			if len(checks) != 1 {
+				t.Fatalf("Expected one check, got %+v instead", checks)
+			}
+			if checks[0].Passed != td.expectedPassed || checks[0].Reason != td.expectedReason || checks[0].State != td.state {
+				t.Errorf("Unexpected check %+v", checks[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright © 2025 Soner Astan <sonerastan@icloud.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package verify_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/soner3/net-scan/scan"
+	"github.com/soner3/net-scan/verify"
+)
+
+// Write the policy to a temporary file
+func setup(t *testing.T, policy string) string {
+	filename := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(filename, []byte(policy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestLoad(t *testing.T) {
+	testData := []struct {
+		name        string
+		policy      string
+		expectedErr error
+	}{
+		{"Valid", "groups:\n  web: [10.0.0.1, 10.0.1.0/30]\nrules:\n  - hosts: [web, example.com]\n    open: [80, https]\n    closed: 22,U:161\n", nil},
+		{"NoRules", "groups:\n  web: [10.0.0.1]\n", verify.ErrPolicy},
+		{"NoHosts", "rules:\n  - open: 80\n", verify.ErrPolicy},
+		{"NoPorts", "rules:\n  - hosts: [10.0.0.1]\n", verify.ErrPolicy},
+		{"InvalidPorts", "rules:\n  - hosts: [10.0.0.1]\n    open: 70000\n", scan.ErrPortValue},
+		{"OpenAndClosed", "rules:\n  - hosts: [10.0.0.1]\n    open: 80\n    closed: 1-1024\n", verify.ErrPolicy},
+		{"InvalidGroup", "groups:\n  web: [10.0.0.0/33]\nrules:\n  - hosts: [web]\n    open: 80\n", verify.ErrPolicy},
+		{"UnknownField", "rules:\n  - hosts: [10.0.0.1]\n    filtered: 80\n", verify.ErrPolicy},
+	}
+
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			_, err := verify.Load(setup(t, td.policy))
+			if td.expectedErr == nil && err != nil {
+				t.Fatalf("Expected no error, got %q instead", err)
+			}
+			if !errors.Is(err, td.expectedErr) {
+				t.Errorf("Expected %q, got %q instead", td.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestTargets(t *testing.T) {
+	policy, err := verify.Load(setup(t, "groups:\n  net: [10.0.0.0/30]\nrules:\n  - hosts: [net, 10.0.0.2, example.com]\n    open: 80\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	targets := slices.Collect(policy.Targets(&policy.Rules[0]))
+	expected := []string{"10.0.0.1", "10.0.0.2", "example.com"}
+	if !slices.Equal(targets, expected) {
+		t.Errorf("Expected %v, got %v instead", expected, targets)
+	}
+	if policy.Rules[0].Name != "rule 1" {
+		t.Errorf("Expected the name rule 1, got %q instead", policy.Rules[0].Name)
+	}
+	if probes := policy.Probes(); probes != 3 {
+		t.Errorf("Expected 3 probes, got %d instead", probes)
+	}
+}
+
+func TestRun(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	open := ln.Addr().(*net.TCPAddr).Port
+
+	// A port which was open a moment ago is closed now
+	closedLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := closedLn.Addr().(*net.TCPAddr).Port
+	closedLn.Close()
+
+	// A UDP port is open if it answers the probe
+	pc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			_, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			pc.WriteTo([]byte("ok"), addr)
+		}
+	}()
+	udp := "U:" + strconv.Itoa(pc.LocalAddr().(*net.UDPAddr).Port)
+
+	policy, err := verify.Load(setup(t, "rules:\n"+
+		"  - name: expected\n    hosts: [127.0.0.1]\n    open: "+strconv.Itoa(open)+","+udp+"\n    closed: "+strconv.Itoa(closed)+"\n"+
+		"  - name: violated\n    hosts: [127.0.0.1]\n    open: "+strconv.Itoa(closed)+"\n    closed: "+strconv.Itoa(open)+","+udp+"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Port states of a tcp4 scan carry the networks tcp4 and udp4
+	for _, network := range []string{"tcp", "tcp4"} {
+		t.Run(network, func(t *testing.T) {
+			checks, err := verify.Run(context.Background(), policy, scan.NewOptions(network, time.Second, 10, 10))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(checks) != 6 {
+				t.Fatalf("Expected 6 checks, got %+v instead", checks)
+			}
+			for _, c := range checks {
+				if c.Passed != (c.Rule == "expected") {
+					t.Errorf("Unexpected outcome of %+v", c)
+				}
+			}
+			if failed := verify.Failed(checks); len(failed) != 3 || failed[0].Rule != "violated" {
+				t.Errorf("Expected the checks of violated to fail, got %+v instead", failed)
+			}
+		})
+	}
+}